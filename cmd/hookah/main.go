@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"expvar"
 	"fmt"
 	"github.com/AdamShannag/hookah/internal/auth"
 	"github.com/AdamShannag/hookah/internal/condition"
@@ -19,6 +20,8 @@ import (
 	"strings"
 	"syscall"
 	"time"
+	// Embedded so time zones in conditions resolve on images without zoneinfo.
+	_ "time/tzdata"
 )
 
 func main() {
@@ -34,11 +37,29 @@ func main() {
 
 	conf := config.New(templateConfigs, templates, auth.NewDefault())
 
-	srv := server.NewServer(conf, condition.NewDefaultEvaluator(resolver.NewPathResolver()))
+	srv, err := server.NewServer(conf, condition.NewDefaultEvaluator(resolver.NewPathResolver()))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Metrics are opt-in and served on their own address, never on the webhook listener.
+	if addr := os.Getenv("METRICS_ADDR"); addr != "" {
+		metrics := http.NewServeMux()
+		metrics.Handle("GET /debug/vars", expvar.Handler())
+		go func() {
+			log.Printf("serving metrics on %s/debug/vars", addr)
+			log.Println(http.ListenAndServe(addr, metrics))
+		}()
+	}
+
 	done := make(chan bool, 1)
 	go gracefulShutdown(srv, done)
 
-	err = srv.ListenAndServe()
+	if srv.TLSConfig != nil {
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		err = srv.ListenAndServe()
+	}
 	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		panic(fmt.Sprintf("http server error: %s", err))
 	}
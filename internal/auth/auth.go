@@ -1,14 +1,19 @@
 package auth
 
 import (
+	"errors"
+	"fmt"
 	"github.com/AdamShannag/hookah/internal/flow"
 	"github.com/AdamShannag/hookah/internal/types"
+	"log"
 	"net/http"
 )
 
+var ErrUnknownFlow = errors.New("unknown flow")
+
 type Auth interface {
 	RegisterFlow(flow string, flowFunc flow.Func) Auth
-	ApplyFlow(auth types.Auth, r *http.Request, payload []byte) bool
+	ApplyFlow(auth types.Auth, r *http.Request, payload []byte) error
 }
 
 type auth struct {
@@ -25,7 +30,13 @@ func NewDefault() Auth {
 		RegisterFlow("plain secret", flow.PlainSecret).
 		RegisterFlow("basic auth", flow.BasicAuth).
 		RegisterFlow("gitlab", flow.Gitlab).
-		RegisterFlow("github", flow.Github)
+		RegisterFlow("github", flow.Github).
+		RegisterFlow("slack", flow.Slack).
+		RegisterFlow("stripe", flow.Stripe).
+		RegisterFlow("standard-webhooks", flow.StandardWebhooks).
+		RegisterFlow("jwt", flow.JWT).
+		RegisterFlow("ip allowlist", flow.IPAllowlist).
+		RegisterFlow("mtls", flow.MTLS)
 }
 
 func (a *auth) RegisterFlow(flow string, flowFunc flow.Func) Auth {
@@ -33,12 +44,73 @@ func (a *auth) RegisterFlow(flow string, flowFunc flow.Func) Auth {
 	return a
 }
 
-func (a *auth) ApplyFlow(auth types.Auth, r *http.Request, payload []byte) bool {
+// ApplyFlow runs the flow named by auth, or evaluates its nested all/any policy. Every
+// sub-policy is evaluated and logged, so a composite result can be traced leaf by leaf.
+// A failed composite reports the first failing sub-policy's error.
+func (a *auth) ApplyFlow(auth types.Auth, r *http.Request, payload []byte) error {
+	switch {
+	case len(auth.All) > 0:
+		var failed error
+		for i, sub := range auth.All {
+			if err := a.applySub(fmt.Sprintf("all[%d]", i), sub, r, payload); err != nil && failed == nil {
+				failed = err
+			}
+		}
+		return failed
+	case len(auth.Any) > 0:
+		var failed error
+		passed := false
+		for i, sub := range auth.Any {
+			if err := a.applySub(fmt.Sprintf("any[%d]", i), sub, r, payload); err == nil {
+				passed = true
+			} else if failed == nil {
+				failed = err
+			}
+		}
+		if passed {
+			return nil
+		}
+		return failed
+	}
+
 	flowFunc, ok := a.flows[auth.Flow]
 	if !ok {
-
-		return false
+		return fmt.Errorf("%w: '%s'", ErrUnknownFlow, auth.Flow)
 	}
 
 	return flowFunc(auth, r, payload)
 }
+
+func (a *auth) applySub(path string, sub types.Auth, r *http.Request, payload []byte) error {
+	err := a.ApplyFlow(sub, r, payload)
+	if err != nil {
+		log.Printf("[AUTH] %s flow: %s failed: %v", path, sub.Name(), err)
+	} else {
+		log.Printf("[AUTH] %s flow: %s passed", path, sub.Name())
+	}
+	return err
+}
+
+// Reason maps an ApplyFlow error to a stable label for logs and metrics.
+func Reason(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrUnknownFlow):
+		return "unknown_flow"
+	case errors.Is(err, flow.ErrMissingHeader):
+		return "missing_header"
+	case errors.Is(err, flow.ErrBadSignature):
+		return "bad_signature"
+	case errors.Is(err, flow.ErrStaleTimestamp):
+		return "stale_timestamp"
+	case errors.Is(err, flow.ErrInvalidToken):
+		return "invalid_token"
+	case errors.Is(err, flow.ErrForbidden):
+		return "forbidden"
+	case errors.Is(err, flow.ErrMisconfigured):
+		return "misconfigured"
+	default:
+		return "other"
+	}
+}
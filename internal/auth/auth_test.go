@@ -3,6 +3,7 @@ package auth_test
 import (
 	"bytes"
 	"github.com/AdamShannag/hookah/internal/auth"
+	"github.com/AdamShannag/hookah/internal/flow"
 	"github.com/AdamShannag/hookah/internal/types"
 	"net/http"
 	"net/http/httptest"
@@ -29,7 +30,7 @@ func TestRegisterAndApplyFlow(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := a.ApplyFlow(tt.auth, req, []byte("test"))
+			result := a.ApplyFlow(tt.auth, req, []byte("test")) == nil
 			if result != tt.expected {
 				t.Errorf("expected %v, got %v", tt.expected, result)
 			}
@@ -37,8 +38,76 @@ func TestRegisterAndApplyFlow(t *testing.T) {
 	}
 }
 
-func mockFlow(expected bool) func(types.Auth, *http.Request, []byte) bool {
-	return func(a types.Auth, r *http.Request, b []byte) bool {
-		return expected
+func TestApplyFlow_Composite(t *testing.T) {
+	a := auth.New().
+		RegisterFlow("pass", mockFlow(true)).
+		RegisterFlow("fail", mockFlow(false))
+
+	req := httptest.NewRequest("POST", "/", nil)
+
+	tests := []struct {
+		name     string
+		auth     types.Auth
+		expected bool
+	}{
+		{"AllPass", types.Auth{All: []types.Auth{{Flow: "pass"}, {Flow: "pass"}}}, true},
+		{"OneFails", types.Auth{All: []types.Auth{{Flow: "pass"}, {Flow: "fail"}}}, false},
+		{"UnknownSubFlow", types.Auth{All: []types.Auth{{Flow: "pass"}, {Flow: "unregistered"}}}, false},
+		{"AnyOnePasses", types.Auth{Any: []types.Auth{{Flow: "fail"}, {Flow: "pass"}}}, true},
+		{"AnyAllFail", types.Auth{Any: []types.Auth{{Flow: "fail"}, {Flow: "unregistered"}}}, false},
+		{"NestedPasses", types.Auth{All: []types.Auth{
+			{Flow: "pass"},
+			{Any: []types.Auth{{Flow: "fail"}, {Flow: "pass"}}},
+		}}, true},
+		{"NestedFails", types.Auth{All: []types.Auth{
+			{Flow: "fail"},
+			{Any: []types.Auth{{Flow: "fail"}, {Flow: "pass"}}},
+		}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := a.ApplyFlow(tt.auth, req, nil) == nil; result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestReason(t *testing.T) {
+	a := auth.New().
+		RegisterFlow("fail", mockFlow(false)).
+		RegisterFlow("github", flow.Github).
+		RegisterFlow("ip allowlist", flow.IPAllowlist)
+
+	req := httptest.NewRequest("POST", "/", nil)
+
+	tests := []struct {
+		name     string
+		auth     types.Auth
+		expected string
+	}{
+		{"UnknownFlow", types.Auth{Flow: "unregistered"}, "unknown_flow"},
+		{"MissingHeader", types.Auth{Flow: "github", HeaderSecretKey: "X-Hub-Signature-256"}, "missing_header"},
+		{"BadSignature", types.Auth{Flow: "fail"}, "bad_signature"},
+		{"InvalidCIDR", types.Auth{Flow: "ip allowlist", AllowedCIDRs: []string{"10.0.0.0/33"}}, "misconfigured"},
+		{"CompositeReportsFirstFailure", types.Auth{Any: []types.Auth{{Flow: "unregistered"}, {Flow: "fail"}}}, "unknown_flow"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if reason := auth.Reason(a.ApplyFlow(tt.auth, req, nil)); reason != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, reason)
+			}
+		})
+	}
+}
+
+func mockFlow(expected bool) flow.Func {
+	return func(a types.Auth, r *http.Request, b []byte) error {
+		if !expected {
+			return flow.ErrBadSignature
+		}
+		return nil
 	}
 }
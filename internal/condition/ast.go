@@ -0,0 +1,153 @@
+package condition
+
+import (
+	"errors"
+	"fmt"
+	"github.com/AdamShannag/hookah/internal/resolver"
+	"net/http"
+)
+
+// input is what a condition is evaluated against.
+type input struct {
+	headers http.Header
+	body    map[string]any
+	missing MissingPolicy
+}
+
+type node interface {
+	eval(e *evaluator, in input) (bool, error)
+}
+
+type andNode struct{ left, right node }
+
+type orNode struct{ left, right node }
+
+type notNode struct{ operand node }
+
+type compareNode struct {
+	op          string
+	fn          OperatorFunc
+	left, right operand
+}
+
+type unaryNode struct {
+	op      string
+	fn      UnaryFunc
+	operand operand
+}
+
+type operandKind int
+
+const (
+	operandLiteral operandKind = iota
+	operandHeader
+	operandBody
+	operandNow
+)
+
+// quantifier applies an operator to every item of a list operand, as in
+// {any Body.commits[].message} {contains} {"[deploy]"}.
+type quantifier int
+
+const (
+	noQuantifier quantifier = iota
+	quantifyAny
+	quantifyAll
+	quantifyNone
+)
+
+var quantifiers = map[string]quantifier{"any": quantifyAny, "all": quantifyAll, "none": quantifyNone}
+
+// operand is a literal value, a canonical header key, or a Body path with its accessor.
+type operand struct {
+	kind       operandKind
+	path       string
+	value      any
+	access     resolver.Accessor
+	quantifier quantifier
+}
+
+func (n *andNode) eval(e *evaluator, in input) (bool, error) {
+	ok, err := n.left.eval(e, in)
+	if err != nil || !ok {
+		return false, err
+	}
+	return n.right.eval(e, in)
+}
+
+func (n *orNode) eval(e *evaluator, in input) (bool, error) {
+	ok, err := n.left.eval(e, in)
+	if err != nil || ok {
+		return ok, err
+	}
+	return n.right.eval(e, in)
+}
+
+func (n *notNode) eval(e *evaluator, in input) (bool, error) {
+	ok, err := n.operand.eval(e, in)
+	return !ok && err == nil, err
+}
+
+func (n *compareNode) eval(e *evaluator, in input) (bool, error) {
+	leftVal, err := e.resolveValue(n.left, in)
+	if err != nil {
+		return false, missingOr(in, fmt.Errorf("left value: %w", err))
+	}
+
+	rightVal, err := e.resolveValue(n.right, in)
+	if err != nil {
+		return false, missingOr(in, fmt.Errorf("right value: %w", err))
+	}
+
+	if n.left.quantifier != noQuantifier {
+		return quantify(n.left.quantifier, leftVal, func(item any) (bool, error) {
+			return n.fn(item, rightVal)
+		})
+	}
+	return n.fn(leftVal, rightVal)
+}
+
+func (n *unaryNode) eval(e *evaluator, in input) (bool, error) {
+	value, exists, err := e.lookup(n.operand, in)
+	if err != nil {
+		return false, fmt.Errorf("value: %w", err)
+	}
+	if exists && n.operand.quantifier != noQuantifier {
+		return quantify(n.operand.quantifier, value, func(item any) (bool, error) {
+			return n.fn(item, true)
+		})
+	}
+	return n.fn(value, exists)
+}
+
+// quantify tests every item of list. An empty list satisfies all and none but not any.
+func quantify(q quantifier, list any, test func(item any) (bool, error)) (bool, error) {
+	items, ok := list.([]any)
+	if !ok {
+		return false, fmt.Errorf("quantified value must be a list, got %T", list)
+	}
+
+	for _, item := range items {
+		ok, err := test(item)
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case ok && q == quantifyAny:
+			return true, nil
+		case !ok && q == quantifyAll:
+			return false, nil
+		case ok && q == quantifyNone:
+			return false, nil
+		}
+	}
+	return q != quantifyAny, nil
+}
+
+// missingOr drops err when it reports a missing path and the policy makes that false.
+func missingOr(in input, err error) error {
+	if in.missing == MissingFalse && errors.Is(err, resolver.ErrNotFound) {
+		return nil
+	}
+	return err
+}
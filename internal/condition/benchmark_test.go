@@ -0,0 +1,69 @@
+package condition
+
+import (
+	"encoding/json"
+	"github.com/AdamShannag/hookah/internal/resolver"
+	"net/http"
+	"testing"
+)
+
+var (
+	benchmarkHeaders = http.Header{"X-Gitlab-Event": []string{"Pipeline Hook"}}
+	benchmarkBody    = map[string]any{
+		"object_attributes": map[string]any{
+			"status":   "failed",
+			"ref":      "release/1.24",
+			"duration": json.Number("754"),
+		},
+		"commits": []any{
+			map[string]any{"message": "fix: retry [deploy]"},
+			map[string]any{"message": "chore: bump"},
+		},
+	}
+	benchmarkConditions = []string{
+		"{Header.X-Gitlab-Event} {eq} {Pipeline Hook}",
+		"({Body.object_attributes.status} {in} {[\"failed\", \"canceled\"]} || {Body.object_attributes.duration} {gt} {600}) && !{Body.object_attributes.ref} {eq} {main}",
+		`{Body.object_attributes.ref} {matches} {^release/\d+\.\d+$}`,
+	}
+)
+
+// BenchmarkParse is the cost evaluation used to pay on every request.
+func BenchmarkParse(b *testing.B) {
+	e := NewDefaultEvaluator(resolver.NewPathResolver()).(*evaluator)
+	for i := 0; i < b.N; i++ {
+		for _, cond := range benchmarkConditions {
+			if _, err := e.parse(cond); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// BenchmarkEvaluateAll evaluates conditions compiled by Validate, as the server does.
+func BenchmarkEvaluateAll(b *testing.B) {
+	e := NewDefaultEvaluator(resolver.NewPathResolver())
+	if err := e.Validate(benchmarkConditions); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if ok, err := e.EvaluateAll(benchmarkConditions, benchmarkHeaders, benchmarkBody); err != nil || !ok {
+			b.Fatalf("EvaluateAll() = %v, %v", ok, err)
+		}
+	}
+}
+
+func BenchmarkEvaluateAllParallel(b *testing.B) {
+	e := NewDefaultEvaluator(resolver.NewPathResolver())
+	if err := e.Validate(benchmarkConditions); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _ = e.EvaluateAll(benchmarkConditions, benchmarkHeaders, benchmarkBody)
+		}
+	})
+}
@@ -0,0 +1,250 @@
+package condition
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// hoursWindow is a daily window such as 09:00-17:00 in a time zone. The start is
+// inclusive and the end exclusive; a window whose end is before its start spans midnight.
+type hoursWindow struct {
+	start, end int
+	loc        *time.Location
+}
+
+// weekdaySet is a set of days such as Mon-Fri in a time zone.
+type weekdaySet struct {
+	days [7]bool
+	loc  *time.Location
+}
+
+// withinHours reports whether the timestamp on the left falls in a window written as
+// "09:00-17:00" or "09:00-17:00 Europe/Berlin"; without a zone UTC is used.
+func withinHours(a, b any) (bool, error) {
+	t, err := toTime(a)
+	if err != nil {
+		return false, err
+	}
+
+	w, ok := b.(hoursWindow)
+	if !ok {
+		compiled, err := compileHours(b)
+		if err != nil {
+			return false, err
+		}
+		w = compiled.(hoursWindow)
+	}
+
+	local := t.In(w.loc)
+	minute := local.Hour()*60 + local.Minute()
+	if w.start <= w.end {
+		return minute >= w.start && minute < w.end, nil
+	}
+	return minute >= w.start || minute < w.end, nil
+}
+
+// weekdayIn reports whether the timestamp on the left falls on one of the days written
+// as "Mon-Fri", "Sat,Sun" or "Mon,Wed-Fri America/New_York"; without a zone UTC is used.
+func weekdayIn(a, b any) (bool, error) {
+	t, err := toTime(a)
+	if err != nil {
+		return false, err
+	}
+
+	set, ok := b.(weekdaySet)
+	if !ok {
+		compiled, err := compileWeekdays(b)
+		if err != nil {
+			return false, err
+		}
+		set = compiled.(weekdaySet)
+	}
+
+	return set.days[t.In(set.loc).Weekday()], nil
+}
+
+// olderThan reports whether the timestamp on the left is further in the past than the
+// duration on the right, such as 1h, 90m or 7d.
+func olderThan(a, b any) (bool, error) {
+	t, d, err := timeAndDuration(a, b)
+	if err != nil {
+		return false, err
+	}
+	return time.Since(t) > d, nil
+}
+
+func newerThan(a, b any) (bool, error) {
+	t, d, err := timeAndDuration(a, b)
+	if err != nil {
+		return false, err
+	}
+	return time.Since(t) < d, nil
+}
+
+func timeAndDuration(a, b any) (time.Time, time.Duration, error) {
+	t, err := toTime(a)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+
+	d, ok := b.(time.Duration)
+	if !ok {
+		compiled, err := compileDuration(b)
+		if err != nil {
+			return time.Time{}, 0, err
+		}
+		d = compiled.(time.Duration)
+	}
+	return t, d, nil
+}
+
+// toTime reads the {Now} operand, an RFC3339 timestamp, or Unix seconds.
+func toTime(v any) (time.Time, error) {
+	switch val := v.(type) {
+	case time.Time:
+		return val, nil
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, val); err == nil {
+			return t, nil
+		}
+		if isNumber(val) {
+			return toTime(json.Number(val))
+		}
+	default:
+		if seconds, ok := toNumber(v); ok {
+			return unixTime(seconds)
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot read %v as a time: expected an RFC3339 timestamp or Unix seconds", v)
+}
+
+// Unix seconds are accepted between the years 1 and 9999; anything larger is more likely
+// milliseconds, which are rejected rather than misread.
+const (
+	minUnixSeconds = -62135596800
+	maxUnixSeconds = 253402300799
+)
+
+func unixTime(seconds *big.Rat) (time.Time, error) {
+	whole := new(big.Int).Quo(seconds.Num(), seconds.Denom())
+	if !whole.IsInt64() || whole.Int64() < minUnixSeconds || whole.Int64() > maxUnixSeconds {
+		return time.Time{}, fmt.Errorf("unix time %s is out of range: expected seconds between years 1 and 9999", seconds.FloatString(0))
+	}
+
+	fraction := new(big.Rat).Sub(seconds, new(big.Rat).SetInt(whole))
+	nanos, _ := fraction.Mul(fraction, big.NewRat(int64(time.Second), 1)).Float64()
+	return time.Unix(whole.Int64(), int64(nanos)), nil
+}
+
+func compileDuration(v any) (any, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("duration must be a string such as 1h or 7d")
+	}
+
+	if days, isDays := strings.CutSuffix(s, "d"); isDays {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid duration '%s'", s)
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid duration '%s'", s)
+	}
+	return d, nil
+}
+
+func compileHours(v any) (any, error) {
+	spec, loc, err := splitZone(v)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to, ok := strings.Cut(spec, "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid hours '%s': expected HH:MM-HH:MM", spec)
+	}
+	start, err := parseClock(from)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return nil, err
+	}
+	return hoursWindow{start: start, end: end, loc: loc}, nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day '%s': expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func compileWeekdays(v any) (any, error) {
+	spec, loc, err := splitZone(v)
+	if err != nil {
+		return nil, err
+	}
+
+	set := weekdaySet{loc: loc}
+	for _, item := range strings.Split(spec, ",") {
+		from, to, isRange := strings.Cut(item, "-")
+		first, err := parseWeekday(from)
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if isRange {
+			if last, err = parseWeekday(to); err != nil {
+				return nil, err
+			}
+		}
+		for day := first; ; day = (day + 1) % 7 {
+			set.days[day] = true
+			if day == last {
+				break
+			}
+		}
+	}
+	return set, nil
+}
+
+func parseWeekday(s string) (time.Weekday, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		full := strings.ToLower(day.String())
+		if name == full || name == full[:3] {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday '%s'", s)
+}
+
+// splitZone separates an optional trailing IANA time zone from a window spec.
+func splitZone(v any) (string, *time.Location, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", nil, fmt.Errorf("window must be a string")
+	}
+
+	spec, zone, hasZone := strings.Cut(strings.TrimSpace(s), " ")
+	if !hasZone {
+		return spec, time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(strings.TrimSpace(zone))
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid time zone '%s': %w", zone, err)
+	}
+	return spec, loc, nil
+}
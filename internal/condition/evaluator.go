@@ -1,25 +1,70 @@
 package condition
 
 import (
+	"errors"
 	"fmt"
 	"github.com/AdamShannag/hookah/internal/resolver"
 	"net/http"
-	"strings"
+	"sync"
+	"time"
 )
 
 type Evaluator interface {
 	Register(op string, fn OperatorFunc) Evaluator
+	// RegisterUnary adds an operator that takes only a left operand, such as {exists}.
+	RegisterUnary(op string, fn UnaryFunc) Evaluator
 	EvaluateAll(conditions []string, headers http.Header, body map[string]any) (bool, error)
+	// EvaluateAllWithPolicy is EvaluateAll with policy applied to paths missing from body.
+	EvaluateAllWithPolicy(policy MissingPolicy, conditions []string, headers http.Header, body map[string]any) (bool, error)
+	// Validate parses conditions ahead of time so that syntax errors and bad patterns
+	// surface when the config loads rather than on the first matching request.
+	Validate(conditions []string) error
 }
 type OperatorFunc func(left, right any) (bool, error)
 
+// UnaryFunc receives the resolved value and whether its path was present at all.
+type UnaryFunc func(value any, exists bool) (bool, error)
+
+// MissingPolicy decides what a comparison does when a Body path is absent.
+type MissingPolicy string
+
+const (
+	// MissingError fails the evaluation; it is the default.
+	MissingError MissingPolicy = "error"
+	// MissingFalse makes the comparison false.
+	MissingFalse MissingPolicy = "false"
+	// MissingNull compares the missing value as null.
+	MissingNull MissingPolicy = "null"
+)
+
+// ParseMissingPolicy reads a policy from config, where an empty value means MissingError.
+func ParseMissingPolicy(s string) (MissingPolicy, error) {
+	switch policy := MissingPolicy(s); policy {
+	case "":
+		return MissingError, nil
+	case MissingError, MissingFalse, MissingNull:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown missing path policy '%s': expected error, false or null", s)
+	}
+}
+
 type evaluator struct {
 	operators map[string]OperatorFunc
+	unary     map[string]UnaryFunc
 	resolver  resolver.Resolver
+
+	mu     sync.RWMutex
+	parsed map[string]node
 }
 
 func NewEvaluator(resolver resolver.Resolver) Evaluator {
-	return &evaluator{operators: map[string]OperatorFunc{}, resolver: resolver}
+	return &evaluator{
+		operators: map[string]OperatorFunc{},
+		unary:     map[string]UnaryFunc{},
+		resolver:  resolver,
+		parsed:    map[string]node{},
+	}
 }
 
 func NewDefaultEvaluator(resolver resolver.Resolver) Evaluator {
@@ -30,7 +75,31 @@ func NewDefaultEvaluator(resolver resolver.Resolver) Evaluator {
 		Register("{notIn}", notIn).
 		Register("{contains}", contains).
 		Register("{startsWith}", startsWith).
-		Register("{endsWith}", endsWith)
+		Register("{endsWith}", endsWith).
+		Register("{gt}", greaterThan).
+		Register("{gte}", greaterOrEqual).
+		Register("{lt}", lessThan).
+		Register("{lte}", lessOrEqual).
+		Register("{between}", between).
+		Register("{matches}", matches).
+		Register("{notMatches}", notMatches).
+		Register("{isType}", isType).
+		Register("{eqIgnoreCase}", equalsIgnoreCase).
+		Register("{containsIgnoreCase}", containsIgnoreCase).
+		Register("{startsWithIgnoreCase}", startsWithIgnoreCase).
+		Register("{endsWithIgnoreCase}", endsWithIgnoreCase).
+		Register("{glob}", glob).
+		Register("{semverGt}", semverGreaterThan).
+		Register("{semverLt}", semverLessThan).
+		Register("{semverMatches}", semverMatches).
+		Register("{withinHours}", withinHours).
+		Register("{weekdayIn}", weekdayIn).
+		Register("{olderThan}", olderThan).
+		Register("{newerThan}", newerThan).
+		RegisterUnary("{exists}", exists).
+		RegisterUnary("{notExists}", notExists).
+		RegisterUnary("{empty}", empty).
+		RegisterUnary("{notEmpty}", notEmpty)
 }
 
 func (e *evaluator) Register(op string, fn OperatorFunc) Evaluator {
@@ -38,9 +107,28 @@ func (e *evaluator) Register(op string, fn OperatorFunc) Evaluator {
 	return e
 }
 
+func (e *evaluator) RegisterUnary(op string, fn UnaryFunc) Evaluator {
+	e.unary[op] = fn
+	return e
+}
+
+func (e *evaluator) Validate(conditions []string) error {
+	for _, cond := range conditions {
+		if _, err := e.compile(cond); err != nil {
+			return fmt.Errorf("invalid condition %q: %w", cond, err)
+		}
+	}
+	return nil
+}
+
 func (e *evaluator) EvaluateAll(conditions []string, headers http.Header, body map[string]any) (bool, error) {
+	return e.EvaluateAllWithPolicy(MissingError, conditions, headers, body)
+}
+
+func (e *evaluator) EvaluateAllWithPolicy(policy MissingPolicy, conditions []string, headers http.Header, body map[string]any) (bool, error) {
+	in := input{headers: headers, body: body, missing: policy}
 	for _, cond := range conditions {
-		match, err := e.evaluateOne(cond, headers, body)
+		match, err := e.evaluateOne(cond, in)
 		if err != nil {
 			return false, err
 		}
@@ -51,52 +139,87 @@ func (e *evaluator) EvaluateAll(conditions []string, headers http.Header, body m
 	return true, nil
 }
 
-func (e *evaluator) evaluateOne(condition string, headers http.Header, body map[string]any) (bool, error) {
-	op, leftRaw, rightRaw, err := e.extractParts(condition)
+func (e *evaluator) evaluateOne(condition string, in input) (bool, error) {
+	n, err := e.compile(condition)
 	if err != nil {
 		return false, err
 	}
+	return n.eval(e, in)
+}
 
-	leftVal, err := e.resolveValue(leftRaw, headers, body)
-	if err != nil {
-		return false, fmt.Errorf("left value: %w", err)
+// compile returns the parsed form of condition, parsing it only the first time it is seen.
+func (e *evaluator) compile(condition string) (node, error) {
+	e.mu.RLock()
+	n, ok := e.parsed[condition]
+	e.mu.RUnlock()
+	if ok {
+		return n, nil
 	}
 
-	rightVal, err := e.resolveValue(rightRaw, headers, body)
+	n, err := e.parse(condition)
 	if err != nil {
-		return false, fmt.Errorf("right value: %w", err)
-	}
-
-	fn, ok := e.operators[op]
-	if !ok {
-		return false, fmt.Errorf("unknown operator: %s", op)
+		return nil, err
 	}
 
-	return fn(leftVal, rightVal)
+	e.mu.Lock()
+	e.parsed[condition] = n
+	e.mu.Unlock()
+	return n, nil
 }
 
-func (e *evaluator) extractParts(condition string) (op, left, right string, err error) {
-	for opr := range e.operators {
-		if strings.Contains(condition, opr) {
-			parts := strings.SplitN(condition, opr, 2)
-			if len(parts) != 2 {
-				return "", "", "", fmt.Errorf("invalid condition: %s", condition)
-			}
-			return opr, strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), nil
+func (e *evaluator) resolveValue(op operand, in input) (any, error) {
+	switch op.kind {
+	case operandHeader:
+		values := in.headers[op.path]
+		if op.quantifier != noQuantifier {
+			return headerList(values), nil
+		}
+		if len(values) > 0 {
+			return values[0], nil
 		}
+		return "", nil
+	case operandBody:
+		value, err := op.access(in.body)
+		if in.missing == MissingNull && errors.Is(err, resolver.ErrNotFound) {
+			return nil, nil
+		}
+		return value, err
+	case operandNow:
+		return time.Now(), nil
+	default:
+		return op.value, nil
 	}
-	return "", "", "", fmt.Errorf("unsupported operator in: %s", condition)
 }
 
-func (e *evaluator) resolveValue(expr string, headers http.Header, body map[string]any) (any, error) {
-	expr = strings.Trim(expr, "{}")
-	switch {
-	case strings.HasPrefix(expr, "Header."):
-		key := strings.TrimPrefix(expr, "Header.")
-		return headers.Get(key), nil
-	case strings.HasPrefix(expr, "Body."):
-		return e.resolver.Resolve(strings.TrimPrefix(expr, "Body."), body)
+// lookup resolves op for unary operators, reporting absence instead of failing on it.
+func (e *evaluator) lookup(op operand, in input) (any, bool, error) {
+	switch op.kind {
+	case operandHeader:
+		values := in.headers[op.path]
+		if len(values) == 0 {
+			return nil, false, nil
+		}
+		if op.quantifier != noQuantifier {
+			return headerList(values), true, nil
+		}
+		return values[0], true, nil
+	case operandBody:
+		value, err := op.access(in.body)
+		if errors.Is(err, resolver.ErrNotFound) {
+			return nil, false, nil
+		}
+		return value, err == nil, err
+	case operandNow:
+		return time.Now(), true, nil
 	default:
-		return expr, nil
+		return op.value, true, nil
+	}
+}
+
+func headerList(values []string) []any {
+	list := make([]any, len(values))
+	for i, v := range values {
+		list[i] = v
 	}
+	return list
 }
@@ -1,6 +1,8 @@
 package condition_test
 
 import (
+	"encoding/json"
+	"errors"
 	"github.com/AdamShannag/hookah/internal/condition"
 	"github.com/AdamShannag/hookah/internal/resolver"
 	"net/http"
@@ -43,6 +45,27 @@ func TestEvaluator_EvaluateAll(t *testing.T) {
 			},
 			want: true,
 		},
+		{
+			name:       "Numbers compare by value",
+			conditions: []string{"{Body.a} {eq} {Body.b}"},
+			body:       map[string]any{"a": json.Number("1.0"), "b": json.Number("1")},
+			want:       true,
+		},
+		{
+			name:       "Large integers compare exactly",
+			conditions: []string{"{Body.a} {ne} {Body.b}"},
+			body:       map[string]any{"a": json.Number("1234567890123456789"), "b": json.Number("1234567890123456788")},
+			want:       true,
+		},
+		{
+			name:       "Number in projected list",
+			conditions: []string{"{Body.id} {in} {Body.items[].id}"},
+			body: map[string]any{
+				"id":    json.Number("1234567890123456789"),
+				"items": []any{map[string]any{"id": json.Number("7")}, map[string]any{"id": json.Number("1234567890123456789")}},
+			},
+			want: true,
+		},
 		{
 			name:       "Header value match",
 			conditions: []string{"{Header.X-Token} {eq} {abc123}"},
@@ -51,7 +74,7 @@ func TestEvaluator_EvaluateAll(t *testing.T) {
 		},
 		{
 			name:       "Unsupported operator",
-			conditions: []string{"{Body.user.name} {gt} {Jane}"},
+			conditions: []string{"{Body.user.name} {like} {Jane}"},
 			body:       map[string]any{"user": map[string]any{"name": "Jane"}},
 			wantErr:    true,
 		},
@@ -83,3 +106,671 @@ func TestEvaluator_EvaluateAll(t *testing.T) {
 		})
 	}
 }
+
+func TestEvaluator_Expressions(t *testing.T) {
+	eval := condition.NewDefaultEvaluator(resolver.NewPathResolver())
+
+	headers := http.Header{"X-Gitlab-Event": []string{"Merge Request Hook"}}
+	body := map[string]any{
+		"labels":        []any{"backend", "urgent"},
+		"target_branch": "develop",
+		"title":         "Draft: refactor (part 1) && more",
+	}
+
+	tests := []struct {
+		name      string
+		condition string
+		want      bool
+	}{
+		{"Or first true", "({urgent} {in} {Body.labels}) || ({Body.target_branch} {eq} {main})", true},
+		{"Or both false", "({docs} {in} {Body.labels}) || ({Body.target_branch} {eq} {main})", false},
+		{"Not", "!({Header.X-Gitlab-Event} {startsWith} {Push})", true},
+		{"Not without parens", "! {Body.target_branch} {eq} {develop}", false},
+		{"And binds tighter than or", "{Body.target_branch} {eq} {main} && {x} {eq} {y} || {urgent} {in} {Body.labels}", true},
+		{"Grouping changes precedence", "{Body.target_branch} {eq} {main} && ({x} {eq} {y} || {urgent} {in} {Body.labels})", false},
+		{"Double negation", "!!({backend} {in} {Body.labels})", true},
+		{"Literal with grammar characters", "{Body.title} {endsWith} {(part 1) && more}", true},
+		{"Short-circuit skips missing field", "{Body.target_branch} {eq} {develop} || {Body.missing} {eq} {x}", true},
+		{"Request example", "({Body.target_branch} {eq} {develop}) || !({Header.X-Gitlab-Event} {startsWith} {Merge})", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := eval.EvaluateAll([]string{tt.condition}, headers, body)
+			if err != nil {
+				t.Fatalf("EvaluateAll() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("EvaluateAll() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluator_SyntaxErrors(t *testing.T) {
+	eval := condition.NewDefaultEvaluator(resolver.NewPathResolver())
+
+	tests := []struct {
+		name      string
+		condition string
+		pos       int
+	}{
+		{"Unbalanced paren", "({a} {eq} {a}", 13},
+		{"Unexpected closing paren", "{a} {eq} {a})", 12},
+		{"Missing right operand", "{a} {eq}", 8},
+		{"Dangling or", "{a} {eq} {a} ||", 15},
+		{"Unterminated brace", "{a} {eq} {a", 9},
+		{"Unknown operator", "{a} {like} {a}", 4},
+		{"Bare word in expression", "{a} {eq} a && {b} {eq} {b}", 9},
+		{"Empty", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := eval.EvaluateAll([]string{tt.condition}, nil, nil)
+
+			var syntaxErr *condition.SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("expected syntax error, got %v", err)
+			}
+			if syntaxErr.Pos != tt.pos {
+				t.Errorf("expected error at position %d, got %d (%v)", tt.pos, syntaxErr.Pos, err)
+			}
+		})
+	}
+}
+
+func TestEvaluator_TypedLiterals(t *testing.T) {
+	eval := condition.NewDefaultEvaluator(resolver.NewPathResolver())
+
+	headers := http.Header{"X-Count": []string{"42"}, "X-Draft": []string{"true"}}
+	body := map[string]any{
+		"iid":           json.Number("42"),
+		"ratio":         json.Number("0.5"),
+		"draft":         false,
+		"assignee":      nil,
+		"target_branch": "release",
+		"code":          "42",
+		"version":       "1.1",
+		"tag":           "2",
+		"labels":        []any{"a", "b"},
+	}
+
+	tests := []struct {
+		name      string
+		condition string
+		want      bool
+	}{
+		{"Integer literal", "{Body.iid} {eq} {42}", true},
+		{"Integer literal mismatch", "{Body.iid} {eq} {43}", false},
+		{"Decimal literal", "{Body.ratio} {eq} {0.50}", true},
+		{"Numeric header coerced", "{Header.X-Count} {eq} {42}", true},
+		{"Numeric string body coerced", "{Body.code} {eq} {42}", true},
+		{"Numeric string must match number text", "{Body.code} {eq} {42.0}", false},
+		{"Version string is not a decimal", "{Body.version} {eq} {1.10}", false},
+		{"Version string keeps exact match", "{Body.version} {eq} {1.1}", true},
+		{"Tag string is not a float", "{Body.tag} {eq} {2.0}", false},
+		{"Tag string ne float", "{Body.tag} {ne} {2.0}", true},
+		{"Quoted literal", `{Body.target_branch} {eq} {"release"}`, true},
+		{"Quoted true is a string", `{Body.draft} {eq} {"false"}`, true},
+		{"Quoted literal keeps braces text", `{Body.target_branch} {ne} {"null"}`, true},
+		{"Bool literal", "{Body.draft} {eq} {false}", true},
+		{"Bool header coerced", "{Header.X-Draft} {eq} {true}", true},
+		{"Bool does not equal string", "{Body.target_branch} {eq} {true}", false},
+		{"Null literal", "{Body.assignee} {eq} {null}", true},
+		{"Null is not empty string", "{Header.X-Missing} {eq} {null}", false},
+		{"List literal in", `{Body.target_branch} {in} {["main", "release"]}`, true},
+		{"List literal notIn", `{Body.target_branch} {notIn} {["main", "develop"]}`, true},
+		{"Number in mixed list", `{Body.iid} {in} {["x", 42]}`, true},
+		{"Lists compare item by item", `{Body.labels} {eq} {["a", "b"]}`, true},
+		{"Lists differ in order", `{Body.labels} {eq} {["b", "a"]}`, false},
+		{"Plain text literal", "{Body.target_branch} {eq} {release}", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := eval.EvaluateAll([]string{tt.condition}, headers, body)
+			if err != nil {
+				t.Fatalf("EvaluateAll() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("EvaluateAll() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := eval.EvaluateAll([]string{`{Body.iid} {in} {["unterminated}`}, headers, body); err == nil {
+		t.Error("expected invalid list literal to stay a string that in rejects")
+	}
+}
+
+func TestEvaluator_LegacyBareOperands(t *testing.T) {
+	eval := condition.NewDefaultEvaluator(resolver.NewPathResolver())
+	headers := http.Header{"X-Gitlab-Event": []string{"Push Hook"}}
+	body := map[string]any{"ref": "refs/heads/main", "title": "Fix (urgent)"}
+
+	tests := []struct {
+		name      string
+		condition string
+		want      bool
+	}{
+		{"Bare right operand", "{Body.ref} {eq} refs/heads/main", true},
+		{"Bare right operand with spaces", "{Header.X-Gitlab-Event} {eq} Push Hook", true},
+		{"Bare left operand", "Body.ref {startsWith} {refs/heads/}", true},
+		{"Bare right operand with parentheses", "{Body.title} {endsWith} (urgent)", true},
+		{"Bare right operand mismatch", "{Body.ref} {eq} refs/heads/dev", false},
+		{"Unary operator", "Body.ref {exists}", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := eval.Validate([]string{tt.condition}); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			got, err := eval.EvaluateAll([]string{tt.condition}, headers, body)
+			if err != nil {
+				t.Fatalf("EvaluateAll() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("EvaluateAll() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluator_BracketedStringLiterals(t *testing.T) {
+	eval := condition.NewDefaultEvaluator(resolver.NewPathResolver())
+	body := map[string]any{"title": "[WIP] Fix login", "quote": `"draft`}
+
+	conditions := []string{
+		"{Body.title} {startsWith} {[WIP]}",
+		"{Body.title} {contains} {[WIP] Fix}",
+		`{Body.quote} {eq} {"draft}`,
+	}
+
+	if err := eval.Validate(conditions); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	got, err := eval.EvaluateAll(conditions, http.Header{}, body)
+	if err != nil || !got {
+		t.Errorf("EvaluateAll() = %v, %v, want true", got, err)
+	}
+}
+
+func TestEvaluator_Ordering(t *testing.T) {
+	eval := condition.NewDefaultEvaluator(resolver.NewPathResolver())
+
+	headers := http.Header{"X-Retries": []string{"3"}}
+	body := map[string]any{
+		"duration":   json.Number("754"),
+		"count":      json.Number("6"),
+		"big":        json.Number("9007199254740993"),
+		"created_at": "2024-05-01T10:00:00Z",
+		"name":       "api",
+	}
+
+	tests := []struct {
+		name      string
+		condition string
+		want      bool
+		wantErr   bool
+	}{
+		{"Greater than", "{Body.duration} {gt} {600}", true, false},
+		{"Greater than false", "{Body.duration} {gt} {754}", false, false},
+		{"Greater or equal", "{Body.duration} {gte} {754}", true, false},
+		{"Less than", "{Body.count} {lt} {5}", false, false},
+		{"Less or equal", "{Body.count} {lte} {6.0}", true, false},
+		{"Numeric header", "{Header.X-Retries} {gte} {3}", true, false},
+		{"Large integers exact", "{Body.big} {gt} {9007199254740992}", true, false},
+		{"Timestamps", "{Body.created_at} {lt} {2024-05-01T11:00:00+00:00}", true, false},
+		{"Timestamps with offset", "{Body.created_at} {gt} {2024-05-01T11:00:00+02:00}", true, false},
+		{"Between inclusive", "{Body.count} {between} {[1, 6]}", true, false},
+		{"Between outside", "{Body.duration} {between} {[0, 600]}", false, false},
+		{"Between timestamps", `{Body.created_at} {between} {["2024-05-01T00:00:00Z", "2024-05-02T00:00:00Z"]}`, true, false},
+		{"Between needs two bounds", "{Body.count} {between} {[1]}", false, true},
+		{"Strings cannot be ordered", "{Body.name} {gt} {abc}", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := eval.EvaluateAll([]string{tt.condition}, headers, body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EvaluateAll() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("EvaluateAll() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluator_Matches(t *testing.T) {
+	eval := condition.NewDefaultEvaluator(resolver.NewPathResolver())
+
+	headers := http.Header{"X-Pattern": []string{`^hotfix/`}}
+	body := map[string]any{
+		"ref":   "release/1.24",
+		"build": json.Number("1024"),
+	}
+
+	tests := []struct {
+		name      string
+		condition string
+		want      bool
+		wantErr   bool
+	}{
+		{"Release branch", `{Body.ref} {matches} {^release/\d+\.\d+$}`, true, false},
+		{"Counted repetition", `{Body.ref} {matches} {^release/\d{1}\.\d{2}$}`, true, false},
+		{"No match", `{Body.ref} {matches} {^main$}`, false, false},
+		{"Not matches", `{Body.ref} {notMatches} {^main$}`, true, false},
+		{"Pattern from header", `{Body.ref} {matches} {Header.X-Pattern}`, false, false},
+		{"Quoted pattern", `{Body.ref} {matches} {"^release/"}`, true, false},
+		{"Left side must be a string", `{Body.build} {matches} {^10}`, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := eval.EvaluateAll([]string{tt.condition}, headers, body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EvaluateAll() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("EvaluateAll() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluator_Validate(t *testing.T) {
+	eval := condition.NewDefaultEvaluator(resolver.NewPathResolver())
+
+	tests := []struct {
+		name       string
+		conditions []string
+		wantErr    bool
+	}{
+		{"Valid", []string{`{Body.ref} {matches} {^release/}`, "{Body.a} {eq} {1} && {Body.b} {gt} {2}"}, false},
+		{"Bad pattern", []string{`{Body.ref} {matches} {release/(}`}, true},
+		{"Pattern must be a string", []string{`{Body.ref} {notMatches} {[1]}`}, true},
+		{"Syntax error", []string{"{Body.a} {eq}"}, true},
+		{"Unknown operator", []string{"{Body.a} {like} {b}"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := eval.Validate(tt.conditions)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				var syntaxErr *condition.SyntaxError
+				if !errors.As(err, &syntaxErr) {
+					t.Errorf("Validate() error = %v, want a *SyntaxError", err)
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluator_PresenceAndTypes(t *testing.T) {
+	eval := condition.NewDefaultEvaluator(resolver.NewPathResolver())
+
+	headers := http.Header{"X-Empty": []string{""}, "X-Token": []string{"abc"}}
+	body := map[string]any{
+		"assignee": nil,
+		"title":    "",
+		"labels":   []any{},
+		"reviewer": map[string]any{"name": "Jane"},
+		"count":    json.Number("3"),
+		"draft":    false,
+	}
+
+	tests := []struct {
+		name      string
+		condition string
+		want      bool
+		wantErr   bool
+	}{
+		{"Exists with null value", "{Body.assignee} {exists}", true, false},
+		{"Exists missing", "{Body.milestone} {exists}", false, false},
+		{"Exists missing parent", "{Body.milestone.title} {exists}", false, false},
+		{"Not exists", "{Body.milestone} {notExists}", true, false},
+		{"Header exists", "{Header.X-Token} {exists}", true, false},
+		{"Header not exists", "{Header.X-Missing} {notExists}", true, false},
+		{"Empty null", "{Body.assignee} {empty}", true, false},
+		{"Empty string", "{Body.title} {empty}", true, false},
+		{"Empty list", "{Body.labels} {empty}", true, false},
+		{"Empty missing", "{Body.milestone} {empty}", true, false},
+		{"Empty header", "{Header.X-Empty} {empty}", true, false},
+		{"Not empty object", "{Body.reviewer} {notEmpty}", true, false},
+		{"Not empty false", "{Body.draft} {notEmpty}", true, false},
+		{"Combined with comparison", "{Body.reviewer} {exists} && {Body.reviewer.name} {eq} {Jane}", true, false},
+		{"Is number", "{Body.count} {isType} {number}", true, false},
+		{"Is boolean", "{Body.draft} {isType} {boolean}", true, false},
+		{"Is null", "{Body.assignee} {isType} {null}", true, false},
+		{"Is array", "{Body.labels} {isType} {array}", true, false},
+		{"Is object", "{Body.reviewer} {isType} {object}", true, false},
+		{"Is not string", "{Body.count} {isType} {string}", false, false},
+		{"Unknown type", "{Body.count} {isType} {integer}", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := eval.EvaluateAll([]string{tt.condition}, headers, body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EvaluateAll() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("EvaluateAll() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluator_MissingPolicy(t *testing.T) {
+	eval := condition.NewDefaultEvaluator(resolver.NewPathResolver())
+	body := map[string]any{"action": "opened"}
+
+	tests := []struct {
+		name      string
+		policy    condition.MissingPolicy
+		condition string
+		want      bool
+		wantErr   bool
+	}{
+		{"Error by default", "", "{Body.assignee.login} {eq} {jane}", false, true},
+		{"Error", condition.MissingError, "{Body.assignee.login} {eq} {jane}", false, true},
+		{"False", condition.MissingFalse, "{Body.assignee.login} {eq} {jane}", false, false},
+		{"False lets or continue", condition.MissingFalse, "{Body.assignee.login} {eq} {jane} || {Body.action} {eq} {opened}", true, false},
+		{"False on right side", condition.MissingFalse, "{Body.action} {eq} {Body.previous}", false, false},
+		{"Null", condition.MissingNull, "{Body.assignee} {eq} {null}", true, false},
+		{"Null not equal", condition.MissingNull, "{Body.assignee} {ne} {jane}", true, false},
+		{"Null still fails on bad types", condition.MissingNull, "{Body.assignee} {contains} {jane}", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := eval.EvaluateAllWithPolicy(tt.policy, []string{tt.condition}, http.Header{}, body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EvaluateAllWithPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("EvaluateAllWithPolicy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMissingPolicy(t *testing.T) {
+	for value, want := range map[string]condition.MissingPolicy{
+		"":      condition.MissingError,
+		"error": condition.MissingError,
+		"false": condition.MissingFalse,
+		"null":  condition.MissingNull,
+	} {
+		got, err := condition.ParseMissingPolicy(value)
+		if err != nil || got != want {
+			t.Errorf("ParseMissingPolicy(%q) = %q, %v, want %q", value, got, err, want)
+		}
+	}
+
+	if _, err := condition.ParseMissingPolicy("skip"); err == nil {
+		t.Error("ParseMissingPolicy(\"skip\") expected an error")
+	}
+}
+
+func TestEvaluator_OperatorResolution(t *testing.T) {
+	eval := condition.NewDefaultEvaluator(resolver.NewPathResolver()).
+		Register("{eqx}", func(left, right any) (bool, error) { return false, nil })
+
+	body := map[string]any{"title": "use {eq} in rules", "state": "open"}
+
+	tests := []struct {
+		name      string
+		condition string
+		want      bool
+	}{
+		{"Literal containing operator text", "{Body.title} {eq} {use {eq} in rules}", true},
+		{"Operator prefixing another", "{Body.state} {eq} {open}", true},
+		{"Longer operator name", "{Body.state} {eqx} {open}", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				got, err := eval.EvaluateAll([]string{tt.condition}, http.Header{}, body)
+				if err != nil {
+					t.Fatalf("EvaluateAll() error = %v", err)
+				}
+				if got != tt.want {
+					t.Fatalf("EvaluateAll() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluator_Quantifiers(t *testing.T) {
+	eval := condition.NewDefaultEvaluator(resolver.NewPathResolver())
+
+	headers := http.Header{"X-Tag": []string{"blue", "green"}}
+	body := map[string]any{
+		"commits": []any{
+			map[string]any{"message": "fix: retry [deploy]", "modified": []any{"src/main.go", "README.md"}},
+			map[string]any{"message": "chore: bump", "modified": []any{"go.mod"}},
+		},
+		"labels": []any{
+			map[string]any{"title": "team::api"},
+			map[string]any{"title": "team::web"},
+		},
+		"sizes":    []any{json.Number("3"), json.Number("12")},
+		"assignee": map[string]any{"login": "jane"},
+		"empty":    []any{},
+	}
+
+	tests := []struct {
+		name      string
+		condition string
+		want      bool
+		wantErr   bool
+	}{
+		{"Any commit message", `{any Body.commits[].message} {contains} {"[deploy]"}`, true, false},
+		{"Any commit message no match", `{any Body.commits[].message} {contains} {"[skip]"}`, false, false},
+		{"All labels", "{all Body.labels[].title} {startsWith} {team::}", true, false},
+		{"All labels fail", "{all Body.labels[].title} {eq} {team::api}", false, false},
+		{"No file under docs", "{none Body.commits[].modified} {startsWith} {docs/}", true, false},
+		{"Some file under src", "{none Body.commits[].modified} {startsWith} {src/}", false, false},
+		{"Any with ordering", "{any Body.sizes} {gt} {10}", true, false},
+		{"All with membership", `{all Body.labels[].title} {in} {["team::api", "team::web"]}`, true, false},
+		{"Any with regex", `{any Body.commits[].modified} {matches} {\.go$}`, true, false},
+		{"Empty list any", "{any Body.empty} {eq} {x}", false, false},
+		{"Empty list all", "{all Body.empty} {eq} {x}", true, false},
+		{"Empty list none", "{none Body.empty} {eq} {x}", true, false},
+		{"Unary operator", "{all Body.labels[].title} {notEmpty}", true, false},
+		{"Header values", "{any Header.X-Tag} {eq} {green}", true, false},
+		{"Combined with not", `!{any Body.commits[].message} {contains} {"[skip]"}`, true, false},
+		{"Not a list", "{any Body.assignee} {eq} {jane}", false, true},
+		{"Operator errors propagate", "{any Body.sizes} {contains} {1}", false, true},
+		{"Plain literal is not quantified", "{any value} {eq} {any value}", true, false},
+		{"Right side cannot be quantified", "{Body.assignee.login} {eq} {any Body.labels[].title}", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := eval.EvaluateAll([]string{tt.condition}, headers, body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EvaluateAll() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("EvaluateAll() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluator_IgnoreCaseAndGlob(t *testing.T) {
+	eval := condition.NewDefaultEvaluator(resolver.NewPathResolver())
+
+	body := map[string]any{
+		"label":   "Bug",
+		"title":   "WIP: Fix Login",
+		"count":   json.Number("2"),
+		"path":    "src/server/routes.go",
+		"readme":  "README.md",
+		"unicode": "docs/ü.md",
+		"commits": []any{
+			map[string]any{"modified": []any{"docs/intro.md", "src/main.go"}},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		condition string
+		want      bool
+		wantErr   bool
+	}{
+		{"Equals ignore case", "{Body.label} {eqIgnoreCase} {bug}", true, false},
+		{"Equals ignore case differs", "{Body.label} {eqIgnoreCase} {bugs}", false, false},
+		{"Equals ignore case non-string", "{Body.count} {eqIgnoreCase} {2}", true, false},
+		{"Contains ignore case", "{Body.title} {containsIgnoreCase} {fix login}", true, false},
+		{"Starts with ignore case", "{Body.title} {startsWithIgnoreCase} {wip:}", true, false},
+		{"Ends with ignore case", "{Body.title} {endsWithIgnoreCase} {LOGIN}", true, false},
+		{"Ignore case needs strings", "{Body.count} {containsIgnoreCase} {2}", false, true},
+		{"Glob double star", "{Body.path} {glob} {src/**/*.go}", true, false},
+		{"Glob double star matches zero segments", "{Body.readme} {glob} {**/*.md}", true, false},
+		{"Glob star stays in segment", "{Body.path} {glob} {src/*.go}", false, false},
+		{"Glob question mark", "{Body.readme} {glob} {README.?d}", true, false},
+		{"Glob trailing double star", "{Body.path} {glob} {src/**}", true, false},
+		{"Glob escapes regex characters", "{Body.readme} {glob} {README+md}", false, false},
+		{"Glob non-ASCII", "{Body.unicode} {glob} {docs/ü.md}", true, false},
+		{"Glob question mark matches one rune", "{Body.unicode} {glob} {docs/?.md}", true, false},
+		{"Glob with quantifier", "{any Body.commits[].modified} {glob} {docs/**}", true, false},
+		{"Glob needs a string", "{Body.count} {glob} {*}", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := eval.EvaluateAll([]string{tt.condition}, http.Header{}, body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EvaluateAll() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("EvaluateAll() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluator_Semver(t *testing.T) {
+	eval := condition.NewDefaultEvaluator(resolver.NewPathResolver())
+
+	tests := []struct {
+		name      string
+		version   string
+		condition string
+		want      bool
+		wantErr   bool
+	}{
+		{"Greater than", "v2.10.0", "{Body.tag} {semverGt} {2.9.1}", true, false},
+		{"Greater than numeric literal", "2.0.1", "{Body.tag} {semverGt} {2}", true, false},
+		{"Less than", "1.9.9", "{Body.tag} {semverLt} {v2.0.0}", true, false},
+		{"Prerelease before release", "2.0.0-rc.1", "{Body.tag} {semverLt} {2.0.0}", true, false},
+		{"Numeric prerelease identifiers", "2.0.0-rc.10", "{Body.tag} {semverGt} {2.0.0-rc.9}", true, false},
+		{"Numeric identifiers sort before alphanumeric", "2.0.0-1", "{Body.tag} {semverLt} {2.0.0-alpha}", true, false},
+		{"Longer prerelease wins", "2.0.0-alpha.1", "{Body.tag} {semverGt} {2.0.0-alpha}", true, false},
+		{"Build metadata ignored", "2.0.0+build.5", "{Body.tag} {semverGt} {2.0.0}", false, false},
+		{"Range", "2.4.0", "{Body.tag} {semverMatches} {>=2.0.0 <3.0.0}", true, false},
+		{"Range upper bound", "3.0.0", "{Body.tag} {semverMatches} {>=2.0.0 <3.0.0}", false, false},
+		{"Range with commas", "2.4.0", "{Body.tag} {semverMatches} {>=2.0.0, <3.0.0}", true, false},
+		{"Range excludes prerelease", "2.5.0-beta.1", "{Body.tag} {semverMatches} {>=2.0.0 <3.0.0}", false, false},
+		{"Stable only", "3.0.0-rc.1", "{Body.tag} {semverMatches} {>=0.0.0}", false, false},
+		{"Prerelease named in range", "3.0.0-rc.2", "{Body.tag} {semverMatches} {>=3.0.0-rc.1}", true, false},
+		{"Caret", "1.9.3", "{Body.tag} {semverMatches} {^1.4.0}", true, false},
+		{"Caret major", "2.0.0", "{Body.tag} {semverMatches} {^1.4.0}", false, false},
+		{"Caret zero major", "0.3.0", "{Body.tag} {semverMatches} {^0.2.1}", false, false},
+		{"Caret excludes next prerelease", "2.0.0-rc.1", "{Body.tag} {semverMatches} {^1.4.0}", false, false},
+		{"Tilde", "1.4.9", "{Body.tag} {semverMatches} {~1.4.2}", true, false},
+		{"Tilde minor", "1.5.0", "{Body.tag} {semverMatches} {~1.4.2}", false, false},
+		{"Wildcard", "1.7.2", "{Body.tag} {semverMatches} {1.x}", true, false},
+		{"Or", "4.1.0", "{Body.tag} {semverMatches} {1.x || >=4.0.0}", true, false},
+		{"Leading wildcard", "3.0.0", "{Body.tag} {semverMatches} {*.0.0}", false, true},
+		{"Exact", "3.0.0", "{Body.tag} {semverMatches} {=3.0.0}", true, false},
+		{"Not equal", "3.0.0", "{Body.tag} {semverMatches} {!=3.0.0}", false, false},
+		{"Invalid version", "latest", "{Body.tag} {semverGt} {1.0.0}", false, true},
+		{"Invalid constraint", "1.0.0", "{Body.tag} {semverMatches} {>=one}", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := map[string]any{"tag": tt.version}
+			got, err := eval.EvaluateAll([]string{tt.condition}, http.Header{}, body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EvaluateAll() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("EvaluateAll() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluator_Clock(t *testing.T) {
+	eval := condition.NewDefaultEvaluator(resolver.NewPathResolver())
+
+	body := map[string]any{
+		// A Wednesday, 08:30 UTC and 10:30 in Berlin.
+		"created_at":     "2024-05-01T08:30:00Z",
+		"late":           "2024-05-01T23:15:00Z",
+		"saturday":       "2024-05-04T12:00:00Z",
+		"epoch":          json.Number("1714552200"),
+		"epoch_fraction": json.Number("1714552200.75"),
+		"epoch_ms":       json.Number("4102444800000"),
+		"future":         "2999-01-01T00:00:00Z",
+		"title":          "not a time",
+	}
+
+	tests := []struct {
+		name      string
+		condition string
+		want      bool
+		wantErr   bool
+	}{
+		{"Within hours UTC", "{Body.created_at} {withinHours} {08:00-17:00}", true, false},
+		{"Outside hours UTC", "{Body.created_at} {withinHours} {09:00-17:00}", false, false},
+		{"Within hours in zone", "{Body.created_at} {withinHours} {09:00-17:00 Europe/Berlin}", true, false},
+		{"End is exclusive", "{Body.created_at} {withinHours} {07:00-08:30}", false, false},
+		{"Overnight window", "{Body.late} {withinHours} {22:00-06:00}", true, false},
+		{"Unix seconds", "{Body.epoch} {withinHours} {08:00-09:00}", true, false},
+		{"Fractional unix seconds", "{Body.epoch_fraction} {withinHours} {08:30-08:31}", true, false},
+		{"Unix milliseconds are out of range", "{Body.epoch_ms} {olderThan} {1h}", false, true},
+		{"Weekday range", "{Body.created_at} {weekdayIn} {Mon-Fri}", true, false},
+		{"Weekday list", "{Body.saturday} {weekdayIn} {Sat,Sun}", true, false},
+		{"Weekday full names", "{Body.saturday} {weekdayIn} {monday-friday}", false, false},
+		{"Weekday wraps around", "{Body.saturday} {weekdayIn} {Fri-Mon}", true, false},
+		{"Weekday in zone", "{Body.late} {weekdayIn} {Thu Asia/Tokyo}", true, false},
+		{"Business hours", "{Body.created_at} {weekdayIn} {Mon-Fri Europe/Berlin} && {Body.created_at} {withinHours} {09:00-17:00 Europe/Berlin}", true, false},
+		{"Now is a weekday or weekend", "{Now} {weekdayIn} {Mon-Sun}", true, false},
+		{"Older than", "{Body.created_at} {olderThan} {1h}", true, false},
+		{"Older than days", "{Body.created_at} {olderThan} {7d}", true, false},
+		{"Future is not older", "{Body.future} {olderThan} {1h}", false, false},
+		{"Newer than", "{Body.future} {newerThan} {1h}", true, false},
+		{"Ordered against now", "{Body.created_at} {lt} {Now}", true, false},
+		{"Not a time", "{Body.title} {olderThan} {1h}", false, true},
+		{"Invalid duration", "{Body.created_at} {olderThan} {soon}", false, true},
+		{"Invalid hours", "{Body.created_at} {withinHours} {9-5}", false, true},
+		{"Invalid weekday", "{Body.created_at} {weekdayIn} {Funday}", false, true},
+		{"Invalid time zone", "{Body.created_at} {withinHours} {09:00-17:00 Mars/Olympus}", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := eval.EvaluateAll([]string{tt.condition}, http.Header{}, body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EvaluateAll() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("EvaluateAll() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,16 +1,22 @@
 package condition
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"regexp"
 	"strings"
+	"time"
 )
 
 func equals(a, b any) (bool, error) {
-	return a == b, nil
+	return equal(a, b), nil
 }
 
 func notEquals(a, b any) (bool, error) {
-	return a != b, nil
+	return !equal(a, b), nil
 }
 
 func in(a any, b any) (bool, error) {
@@ -19,7 +25,7 @@ func in(a any, b any) (bool, error) {
 		return false, fmt.Errorf("right side must be a list")
 	}
 	for _, item := range list {
-		if item == a {
+		if equal(item, a) {
 			return true, nil
 		}
 	}
@@ -41,7 +47,7 @@ func notIn(a any, b any) (bool, error) {
 		return false, fmt.Errorf("right side must be a list")
 	}
 	for _, item := range list {
-		if item == a {
+		if equal(item, a) {
 			return false, nil
 		}
 	}
@@ -65,3 +71,358 @@ func endsWith(a, b any) (bool, error) {
 	}
 	return strings.HasSuffix(as, bs), nil
 }
+
+// equal applies the coercion rules shared by eq, ne, in and notIn:
+//
+//   - numbers (json.Number, float64, ints) compare by exact value, so 1.0 equals 1
+//   - a string equals a number only when it is exactly the number's text, so "42"
+//     equals 42 but "1.1" does not equal 1.10 and "2" does not equal 2.0
+//   - a string compared with a bool equals it only when it is "true" or "false"
+//   - lists are equal when they have the same length and equal items in order
+//   - null equals only null; everything else must match in type and value
+func equal(a, b any) bool {
+	a, b = coerce(a, b), coerce(b, a)
+
+	if x, ok := toNumber(a); ok {
+		if y, ok := toNumber(b); ok {
+			return x.Cmp(y) == 0
+		}
+		return false
+	}
+
+	switch av := a.(type) {
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equal(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		return reflect.DeepEqual(a, b)
+	default:
+		if _, isMap := b.(map[string]any); isMap {
+			return false
+		}
+		if _, isList := b.([]any); isList {
+			return false
+		}
+		return a == b
+	}
+}
+
+// coerce converts a string to the type of other when the string spells a value of that
+// type; for a number the string must be written exactly as the number is.
+func coerce(value, other any) any {
+	s, ok := value.(string)
+	if !ok {
+		return value
+	}
+
+	switch other.(type) {
+	case bool:
+		if s == "true" || s == "false" {
+			return s == "true"
+		}
+	default:
+		if _, isNum := toNumber(other); isNum && s == fmt.Sprint(other) {
+			return other
+		}
+	}
+	return value
+}
+
+func toNumber(v any) (*big.Rat, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		return new(big.Rat).SetString(n.String())
+	case float64:
+		if math.IsNaN(n) || math.IsInf(n, 0) {
+			return nil, false
+		}
+		return new(big.Rat).SetFloat64(n), true
+	case float32:
+		return toNumber(float64(n))
+	case int:
+		return new(big.Rat).SetInt64(int64(n)), true
+	case int64:
+		return new(big.Rat).SetInt64(n), true
+	case int32:
+		return new(big.Rat).SetInt64(int64(n)), true
+	case uint64:
+		return new(big.Rat).SetInt(new(big.Int).SetUint64(n)), true
+	default:
+		return nil, false
+	}
+}
+
+func greaterThan(a, b any) (bool, error) {
+	c, err := order(a, b)
+	return c > 0, err
+}
+
+func greaterOrEqual(a, b any) (bool, error) {
+	c, err := order(a, b)
+	return c >= 0, err
+}
+
+func lessThan(a, b any) (bool, error) {
+	c, err := order(a, b)
+	return c < 0, err
+}
+
+func lessOrEqual(a, b any) (bool, error) {
+	c, err := order(a, b)
+	return c <= 0, err
+}
+
+// between reports whether a lies within the inclusive [low, high] list on the right.
+func between(a, b any) (bool, error) {
+	bounds, ok := b.([]any)
+	if !ok || len(bounds) != 2 {
+		return false, fmt.Errorf("right side must be a list of two bounds")
+	}
+
+	low, err := order(a, bounds[0])
+	if err != nil {
+		return false, err
+	}
+	high, err := order(a, bounds[1])
+	if err != nil {
+		return false, err
+	}
+	return low >= 0 && high <= 0, nil
+}
+
+// order compares two numbers (numeric strings included) or two RFC3339 timestamps, where
+// either timestamp may be {Now}.
+func order(a, b any) (int, error) {
+	if x, ok := toOrderedNumber(a); ok {
+		if y, ok := toOrderedNumber(b); ok {
+			return x.Cmp(y), nil
+		}
+	}
+
+	if at, ok := toTimestamp(a); ok {
+		if bt, ok := toTimestamp(b); ok {
+			return at.Compare(bt), nil
+		}
+	}
+
+	return 0, fmt.Errorf("cannot order %v and %v: both sides must be numbers or RFC3339 timestamps", a, b)
+}
+
+func toTimestamp(v any) (time.Time, bool) {
+	switch val := v.(type) {
+	case time.Time:
+		return val, true
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, val)
+		return t, err == nil
+	default:
+		return time.Time{}, false
+	}
+}
+
+func toOrderedNumber(v any) (*big.Rat, bool) {
+	if s, ok := v.(string); ok && isNumber(s) {
+		v = json.Number(s)
+	}
+	return toNumber(v)
+}
+
+// matches reports whether a matches the RE2 pattern on the right. Literal patterns are
+// compiled when the condition is parsed; patterns read from the request are compiled here.
+func matches(a, b any) (bool, error) {
+	return matchCompiled(a, b, compilePattern)
+}
+
+// matchCompiled matches a against b, compiling b with compile unless the parser already did.
+func matchCompiled(a, b any, compile func(any) (any, error)) (bool, error) {
+	as, ok := a.(string)
+	if !ok {
+		return false, fmt.Errorf("left side must be a string")
+	}
+
+	re, ok := b.(*regexp.Regexp)
+	if !ok {
+		compiled, err := compile(b)
+		if err != nil {
+			return false, err
+		}
+		re = compiled.(*regexp.Regexp)
+	}
+	return re.MatchString(as), nil
+}
+
+func notMatches(a, b any) (bool, error) {
+	ok, err := matches(a, b)
+	return !ok && err == nil, err
+}
+
+func compilePattern(v any) (any, error) {
+	var pattern string
+	switch p := v.(type) {
+	case string:
+		pattern = p
+	case json.Number:
+		pattern = p.String()
+	default:
+		return nil, fmt.Errorf("pattern must be a string")
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	return re, nil
+}
+
+func exists(_ any, found bool) (bool, error) {
+	return found, nil
+}
+
+func notExists(_ any, found bool) (bool, error) {
+	return !found, nil
+}
+
+// empty reports whether the value is missing, null, "", or an empty list or object.
+func empty(v any, found bool) (bool, error) {
+	return !found || isEmptyValue(v), nil
+}
+
+func notEmpty(v any, found bool) (bool, error) {
+	return found && !isEmptyValue(v), nil
+}
+
+func isEmptyValue(v any) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case string:
+		return val == ""
+	case []any:
+		return len(val) == 0
+	case map[string]any:
+		return len(val) == 0
+	default:
+		return false
+	}
+}
+
+// isType reports whether a has the JSON type named on the right: string, number, boolean,
+// array, object or null.
+func isType(a, b any) (bool, error) {
+	name, err := checkTypeName(b)
+	if err != nil {
+		return false, err
+	}
+	return typeName(a) == name, nil
+}
+
+func checkTypeName(v any) (any, error) {
+	switch v {
+	case nil:
+		return "null", nil
+	case "string", "number", "boolean", "array", "object", "null":
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unknown type %v: expected string, number, boolean, array, object or null", v)
+	}
+}
+
+func typeName(v any) string {
+	if _, ok := toNumber(v); ok {
+		return "number"
+	}
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// equalsIgnoreCase compares strings case-insensitively and anything else like eq.
+func equalsIgnoreCase(a, b any) (bool, error) {
+	as, aok := a.(string)
+	bs, bok := b.(string)
+	if aok && bok {
+		return strings.EqualFold(as, bs), nil
+	}
+	return equal(a, b), nil
+}
+
+func containsIgnoreCase(a, b any) (bool, error) {
+	return contains(lowerString(a), lowerString(b))
+}
+
+func startsWithIgnoreCase(a, b any) (bool, error) {
+	return startsWith(lowerString(a), lowerString(b))
+}
+
+func endsWithIgnoreCase(a, b any) (bool, error) {
+	return endsWith(lowerString(a), lowerString(b))
+}
+
+func lowerString(v any) any {
+	if s, ok := v.(string); ok {
+		return strings.ToLower(s)
+	}
+	return v
+}
+
+// glob matches a against a path pattern: * and ? match within one path segment, while **
+// matches any number of whole segments, so src/**/*.go matches src/main.go and src/a/b.go.
+func glob(a, b any) (bool, error) {
+	return matchCompiled(a, b, compileGlob)
+}
+
+func compileGlob(v any) (any, error) {
+	var pattern string
+	switch p := v.(type) {
+	case string:
+		pattern = p
+	case json.Number:
+		pattern = p.String()
+	default:
+		return nil, fmt.Errorf("glob must be a string")
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "/**") && i+3 == len(pattern):
+			expr.WriteString("(?:/.*)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case c == '*':
+			expr.WriteString("[^/]*")
+		case c == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	expr.WriteString("$")
+
+	return regexp.Compile(expr.String())
+}
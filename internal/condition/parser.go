@@ -0,0 +1,402 @@
+package condition
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/AdamShannag/hookah/internal/decode"
+	"github.com/AdamShannag/hookah/internal/resolver"
+	"net/textproto"
+	"strings"
+)
+
+// Condition grammar:
+//
+//	expr       := and ( "||" and )*
+//	and        := unary ( "&&" unary )*
+//	unary      := "!" unary | "(" expr ")" | comparison
+//	comparison := operand operator operand | operand unary
+//
+// A single comparison may also keep the older form with unbraced operands, such as
+// {Body.ref} {eq} refs/heads/main; see legacyTokens.
+//
+// Operands and operators are brace groups such as {Body.user.name}, {eq} or {Jane}; an
+// operator is any brace group registered on the evaluator, and a unary one such as
+// {exists} takes no right operand. A left operand prefixed with any, all or none applies
+// the operator to each item of the list it resolves to.
+
+type tokenKind int
+
+const (
+	tokenBrace tokenKind = iota
+	tokenLParen
+	tokenRParen
+	tokenNot
+	tokenAnd
+	tokenOr
+	tokenEOF
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of condition"
+	}
+	return fmt.Sprintf("'%s'", t.text)
+}
+
+// SyntaxError reports where a condition failed to parse.
+type SyntaxError struct {
+	Condition string
+	Pos       int
+	Msg       string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d in %q: %s", e.Pos, e.Condition, e.Msg)
+}
+
+func tokenize(condition string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(condition); {
+		switch c := condition[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '{':
+			end := closingBrace(condition, i)
+			if end < 0 {
+				return nil, &SyntaxError{condition, i, "unterminated '{'"}
+			}
+			tokens = append(tokens, token{tokenBrace, condition[i : end+1], i})
+			i = end + 1
+		case c == '(':
+			tokens = append(tokens, token{tokenLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokenRParen, ")", i})
+			i++
+		case c == '!':
+			tokens = append(tokens, token{tokenNot, "!", i})
+			i++
+		case strings.HasPrefix(condition[i:], "&&"):
+			tokens = append(tokens, token{tokenAnd, "&&", i})
+			i += 2
+		case strings.HasPrefix(condition[i:], "||"):
+			tokens = append(tokens, token{tokenOr, "||", i})
+			i += 2
+		default:
+			return nil, &SyntaxError{condition, i, fmt.Sprintf("unexpected character '%c'", c)}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(condition)}), nil
+}
+
+// literalPreparers convert a literal right operand into the form its operator works on,
+// once at parse time; {matches} receives a compiled *regexp.Regexp instead of a string.
+var literalPreparers = map[string]func(any) (any, error){
+	"{matches}":       compilePattern,
+	"{notMatches}":    compilePattern,
+	"{isType}":        checkTypeName,
+	"{glob}":          compileGlob,
+	"{semverGt}":      prepareVersion,
+	"{semverLt}":      prepareVersion,
+	"{semverMatches}": compileConstraint,
+	"{withinHours}":   compileHours,
+	"{weekdayIn}":     compileWeekdays,
+	"{olderThan}":     compileDuration,
+	"{newerThan}":     compileDuration,
+}
+
+// closingBrace returns the index of the '}' matching the '{' at start, or -1.
+func closingBrace(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// isLegacyOperand reports whether s is a left operand of the legacy form: a plain word
+// or one complete brace group, but not the start of an expression.
+func isLegacyOperand(s string) bool {
+	switch {
+	case s == "", strings.HasPrefix(s, "("), strings.HasPrefix(s, "!"):
+		return false
+	case strings.HasPrefix(s, "{"):
+		return closingBrace(s, 0) == len(s)-1
+	default:
+		return true
+	}
+}
+
+type parser struct {
+	condition string
+	tokens    []token
+	pos       int
+	operators map[string]OperatorFunc
+	unary     map[string]UnaryFunc
+	resolver  resolver.Resolver
+}
+
+func (e *evaluator) parse(condition string) (node, error) {
+	tokens, err := tokenize(condition)
+	if err == nil {
+		var n node
+		if n, err = e.parseTokens(condition, tokens); err == nil {
+			return n, nil
+		}
+	}
+
+	if legacy, ok := e.legacyTokens(condition); ok {
+		if n, legacyErr := e.parseTokens(condition, legacy); legacyErr == nil {
+			return n, nil
+		}
+	}
+	return nil, err
+}
+
+func (e *evaluator) parseTokens(condition string, tokens []token) (node, error) {
+	p := &parser{condition: condition, tokens: tokens, operators: e.operators, unary: e.unary, resolver: e.resolver}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if next := p.peek(); next.kind != tokenEOF {
+		return nil, p.errorf(next, "unexpected %s", next)
+	}
+	return n, nil
+}
+
+// legacyTokens reads a condition in the form accepted before expressions were added:
+// a single comparison where the text on each side of the first operator is an operand,
+// braces optional, as in {Body.ref} {eq} refs/heads/main. It is only tried when the
+// condition does not parse as an expression.
+func (e *evaluator) legacyTokens(condition string) ([]token, bool) {
+	for i := 0; i < len(condition); i++ {
+		if condition[i] != '{' {
+			continue
+		}
+		end := closingBrace(condition, i)
+		if end < 0 {
+			return nil, false
+		}
+
+		op := condition[i : end+1]
+		_, binary := e.operators[op]
+		_, unary := e.unary[op]
+		if !binary && !unary {
+			i = end
+			continue
+		}
+
+		left := strings.TrimSpace(condition[:i])
+		right := strings.TrimSpace(condition[end+1:])
+		if !isLegacyOperand(left) || (strings.HasPrefix(right, "{") && closingBrace(right, 0) != len(right)-1) ||
+			strings.Contains(condition, "&&") || strings.Contains(condition, "||") ||
+			strings.Count(condition, "(") != strings.Count(condition, ")") {
+			return nil, false
+		}
+
+		tokens := []token{
+			{tokenBrace, "{" + strings.Trim(left, "{}") + "}", 0},
+			{tokenBrace, op, i},
+		}
+		if right != "" {
+			tokens = append(tokens, token{tokenBrace, "{" + strings.Trim(right, "{}") + "}", end + 1})
+		}
+		return append(tokens, token{kind: tokenEOF, pos: len(condition)}), true
+	}
+	return nil, false
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return &SyntaxError{Condition: p.condition, Pos: t.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenAnd {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	switch t := p.peek(); t.kind {
+	case tokenNot:
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand}, nil
+	case tokenLParen:
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, p.errorf(closing, "expected ')' but found %s", closing)
+		}
+		return inner, nil
+	case tokenBrace:
+		return p.parseComparison()
+	default:
+		return nil, p.errorf(t, "expected comparison but found %s", t)
+	}
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left := p.next()
+
+	op := p.next()
+	if op.kind != tokenBrace {
+		return nil, p.errorf(op, "expected operator after %s but found %s", left, op)
+	}
+	if fn, ok := p.unary[op.text]; ok {
+		operand, err := p.parseOperand(left)
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: op.text, fn: fn, operand: operand}, nil
+	}
+	fn, ok := p.operators[op.text]
+	if !ok {
+		return nil, p.errorf(op, "unknown operator %s", op)
+	}
+
+	right := p.next()
+	if right.kind != tokenBrace {
+		return nil, p.errorf(right, "expected operand after %s but found %s", op, right)
+	}
+
+	leftOperand, err := p.parseOperand(left)
+	if err != nil {
+		return nil, err
+	}
+	rightOperand, err := p.parseOperand(right)
+	if err != nil {
+		return nil, err
+	}
+	if rightOperand.quantifier != noQuantifier {
+		return nil, p.errorf(right, "only the left operand can be quantified")
+	}
+	if prepare, ok := literalPreparers[op.text]; ok && rightOperand.kind == operandLiteral {
+		if rightOperand.value, err = prepare(rightOperand.value); err != nil {
+			return nil, p.errorf(right, "invalid operand %s for %s: %v", right, op, err)
+		}
+	}
+
+	return &compareNode{op: op.text, fn: fn, left: leftOperand, right: rightOperand}, nil
+}
+
+// parseOperand classifies a brace group. {Header.X} and {Body.path} are read from the
+// request, optionally quantified as {any Body.path}, {all Body.path} or {none Body.path},
+// and {Now} is the current time; anything else is a literal:
+//
+//	{42}, {-1.5e3}       number (json.Number)
+//	{true}, {false}      bool
+//	{null}               nil
+//	{["main", 1]}        list, written as a JSON array
+//	{"null"}             string, written as a JSON string to avoid the typing above
+//	{main}, {[WIP]}      any other text, including invalid JSON, is a plain string
+func (p *parser) parseOperand(t token) (operand, error) {
+	text := strings.TrimSuffix(strings.TrimPrefix(t.text, "{"), "}")
+
+	var quant quantifier
+	if word, rest, ok := strings.Cut(text, " "); ok && quantifiers[word] != noQuantifier &&
+		(strings.HasPrefix(rest, "Header.") || strings.HasPrefix(rest, "Body.")) {
+		quant, text = quantifiers[word], rest
+	}
+
+	switch {
+	case strings.HasPrefix(text, "Header."):
+		key := textproto.CanonicalMIMEHeaderKey(strings.TrimPrefix(text, "Header."))
+		return operand{kind: operandHeader, path: key, quantifier: quant}, nil
+	case strings.HasPrefix(text, "Body."):
+		path := strings.TrimPrefix(text, "Body.")
+		return operand{kind: operandBody, path: path, access: p.accessor(path), quantifier: quant}, nil
+	case text == "Now":
+		return operand{kind: operandNow}, nil
+	case text == "true", text == "false":
+		return operand{kind: operandLiteral, value: text == "true"}, nil
+	case text == "null":
+		return operand{kind: operandLiteral, value: nil}, nil
+	case isNumber(text):
+		return operand{kind: operandLiteral, value: json.Number(text)}, nil
+	case strings.HasPrefix(text, "["), strings.HasPrefix(text, `"`):
+		var value any
+		if err := decode.JSON([]byte(text), &value); err != nil {
+			// Not JSON after all, such as {[WIP]}: keep it as the plain string it always was.
+			return operand{kind: operandLiteral, value: text}, nil
+		}
+		return operand{kind: operandLiteral, value: value}, nil
+	default:
+		return operand{kind: operandLiteral, value: text}, nil
+	}
+}
+
+// accessor parses path once when the resolver supports it.
+func (p *parser) accessor(path string) resolver.Accessor {
+	if c, ok := p.resolver.(resolver.Compiler); ok {
+		return c.Compile(path)
+	}
+	res := p.resolver
+	return func(data any) (any, error) {
+		return res.Resolve(path, data)
+	}
+}
+
+// isNumber reports whether s is a number in JSON syntax.
+func isNumber(s string) bool {
+	return s != "" && (s[0] == '-' || (s[0] >= '0' && s[0] <= '9')) && json.Valid([]byte(s))
+}
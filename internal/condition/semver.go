@@ -0,0 +1,318 @@
+package condition
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// version is a semantic version. A leading "v" and build metadata are ignored, and
+// missing minor or patch numbers read as zero, so tags such as v2 or 1.4 are accepted.
+type version struct {
+	major, minor, patch uint64
+	pre                 []string
+	// parts is how many of major, minor and patch were written.
+	parts int
+}
+
+func parseVersion(s string) (version, error) {
+	text := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	text, _, _ = strings.Cut(text, "+")
+	core, pre, hasPre := strings.Cut(text, "-")
+
+	var v version
+	numbers := strings.Split(core, ".")
+	if len(numbers) > 3 {
+		return version{}, fmt.Errorf("invalid version '%s'", s)
+	}
+	for i, number := range numbers {
+		n, err := strconv.ParseUint(number, 10, 64)
+		if err != nil {
+			return version{}, fmt.Errorf("invalid version '%s'", s)
+		}
+		switch i {
+		case 0:
+			v.major = n
+		case 1:
+			v.minor = n
+		case 2:
+			v.patch = n
+		}
+	}
+	v.parts = len(numbers)
+
+	if hasPre {
+		v.pre = strings.Split(pre, ".")
+		for _, id := range v.pre {
+			if id == "" {
+				return version{}, fmt.Errorf("invalid prerelease in version '%s'", s)
+			}
+		}
+	}
+	return v, nil
+}
+
+// compare orders versions by precedence: a prerelease sorts before its release, and
+// prerelease identifiers compare numerically when numeric and lexically otherwise.
+func (v version) compare(o version) int {
+	for _, pair := range [][2]uint64{{v.major, o.major}, {v.minor, o.minor}, {v.patch, o.patch}} {
+		if pair[0] != pair[1] {
+			if pair[0] < pair[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case len(v.pre) == 0 && len(o.pre) == 0:
+		return 0
+	case len(v.pre) == 0:
+		return 1
+	case len(o.pre) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(v.pre) && i < len(o.pre); i++ {
+		if c := comparePrerelease(v.pre[i], o.pre[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInts(len(v.pre), len(o.pre))
+}
+
+func comparePrerelease(a, b string) int {
+	x, aErr := strconv.ParseUint(a, 10, 64)
+	y, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		if x == y {
+			return 0
+		}
+		if x < y {
+			return -1
+		}
+		return 1
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (v version) sameCore(o version) bool {
+	return v.major == o.major && v.minor == o.minor && v.patch == o.patch
+}
+
+// comparator is a single bound such as >=2.0.0.
+type comparator struct {
+	op      string
+	version version
+}
+
+func (c comparator) allows(v version) bool {
+	cmp := v.compare(c.version)
+	switch c.op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case "!=":
+		return cmp != 0
+	default:
+		return cmp == 0
+	}
+}
+
+// constraint is a set of ranges joined by ||; each range is comparators that must all hold.
+type constraint [][]comparator
+
+// parseConstraint reads constraints such as ">=2.0.0 <3.0.0", "^1.4", "~2.3.1" or
+// "1.x || >=3.0.0-rc.1". Comparators in a range are separated by spaces or commas.
+func parseConstraint(s string) (constraint, error) {
+	var c constraint
+	for _, group := range strings.Split(s, "||") {
+		var comparators []comparator
+		for _, field := range strings.FieldsFunc(group, func(r rune) bool { return r == ' ' || r == ',' }) {
+			parsed, err := parseComparator(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid constraint '%s': %w", s, err)
+			}
+			comparators = append(comparators, parsed...)
+		}
+		if len(comparators) == 0 {
+			return nil, fmt.Errorf("invalid constraint '%s': empty range", s)
+		}
+		c = append(c, comparators)
+	}
+	return c, nil
+}
+
+func parseComparator(field string) ([]comparator, error) {
+	op := ""
+	for _, prefix := range []string{">=", "<=", "!=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(field, prefix) {
+			op, field = prefix, strings.TrimPrefix(field, prefix)
+			break
+		}
+	}
+
+	if field == "*" || field == "x" {
+		return []comparator{{">=", version{parts: 1}}}, nil
+	}
+
+	wildcard := false
+	if trimmed := strings.TrimSuffix(strings.TrimSuffix(field, ".x"), ".*"); trimmed != field {
+		field, wildcard = trimmed, true
+	}
+
+	v, err := parseVersion(field)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case op == "^":
+		return []comparator{{">=", v}, {"<", caretLimit(v)}}, nil
+	case op == "~", (op == "" || op == "=") && (wildcard || v.parts < 3):
+		return []comparator{{">=", v}, {"<", tildeLimit(v)}}, nil
+	default:
+		return []comparator{{op, v}}, nil
+	}
+}
+
+// caretLimit is the first version that changes the leftmost non-zero number.
+func caretLimit(v version) version {
+	switch {
+	case v.major > 0 || v.parts == 1:
+		return version{major: v.major + 1, pre: []string{"0"}}
+	case v.minor > 0 || v.parts == 2:
+		return version{minor: v.minor + 1, pre: []string{"0"}}
+	default:
+		return version{patch: v.patch + 1, pre: []string{"0"}}
+	}
+}
+
+// tildeLimit is the first version past the last number written, keeping patch updates
+// for ~1.2.3 and ~1.2, and minor updates for ~1.
+func tildeLimit(v version) version {
+	if v.parts == 1 {
+		return version{major: v.major + 1, pre: []string{"0"}}
+	}
+	return version{major: v.major, minor: v.minor + 1, pre: []string{"0"}}
+}
+
+// allows reports whether v satisfies any range. A prerelease only satisfies a range that
+// names a prerelease of the same major.minor.patch, so >=2.0.0 excludes 3.0.0-beta.1.
+func (c constraint) allows(v version) bool {
+	for _, comparators := range c {
+		if rangeAllows(comparators, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func rangeAllows(comparators []comparator, v version) bool {
+	for _, cmp := range comparators {
+		if !cmp.allows(v) {
+			return false
+		}
+	}
+	if len(v.pre) == 0 {
+		return true
+	}
+	// Limits derived from ^, ~ and wildcards have no written parts and never admit prereleases.
+	for _, cmp := range comparators {
+		if cmp.version.parts > 0 && len(cmp.version.pre) > 0 && cmp.version.sameCore(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func semverGreaterThan(a, b any) (bool, error) {
+	c, err := compareVersions(a, b)
+	return c > 0, err
+}
+
+func semverLessThan(a, b any) (bool, error) {
+	c, err := compareVersions(a, b)
+	return c < 0, err
+}
+
+func compareVersions(a, b any) (int, error) {
+	x, err := toVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	y, err := toVersion(b)
+	if err != nil {
+		return 0, err
+	}
+	return x.compare(y), nil
+}
+
+// semverMatches reports whether the version on the left satisfies the constraint on the right.
+func semverMatches(a, b any) (bool, error) {
+	v, err := toVersion(a)
+	if err != nil {
+		return false, err
+	}
+
+	c, ok := b.(constraint)
+	if !ok {
+		compiled, err := compileConstraint(b)
+		if err != nil {
+			return false, err
+		}
+		c = compiled.(constraint)
+	}
+	return c.allows(v), nil
+}
+
+func toVersion(v any) (version, error) {
+	switch val := v.(type) {
+	case version:
+		return val, nil
+	case string:
+		return parseVersion(val)
+	case json.Number:
+		return parseVersion(val.String())
+	default:
+		return version{}, fmt.Errorf("version must be a string, got %T", v)
+	}
+}
+
+func prepareVersion(v any) (any, error) {
+	return toVersion(v)
+}
+
+func compileConstraint(v any) (any, error) {
+	switch val := v.(type) {
+	case string:
+		return parseConstraint(val)
+	case json.Number:
+		return parseConstraint(val.String())
+	default:
+		return nil, fmt.Errorf("constraint must be a string")
+	}
+}
@@ -1,12 +1,20 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"github.com/AdamShannag/hookah/internal/auth"
+	"github.com/AdamShannag/hookah/internal/metrics"
 	"github.com/AdamShannag/hookah/internal/types"
 	"log"
 	"net/http"
 )
 
+var (
+	ErrUnknownReceiver = errors.New("unknown receiver")
+	ErrUnauthorized    = errors.New("no template authenticated")
+)
+
 type Config struct {
 	templateConfigs []types.Template
 	templates       map[string]string
@@ -29,18 +37,55 @@ func (c *Config) GetTemplate(template string) string {
 	return body
 }
 
-func (c *Config) GetConfigTemplates(receiver string, r *http.Request, payload []byte) (templates []types.Template) {
+// GetTemplateConfigs returns every configured template.
+func (c *Config) GetTemplateConfigs() []types.Template {
+	return c.templateConfigs
+}
+
+// GetReceiverTemplates returns the receiver's templates without applying auth.
+func (c *Config) GetReceiverTemplates(receiver string) (templates []types.Template) {
+	for _, template := range c.templateConfigs {
+		if template.Receiver == receiver {
+			templates = append(templates, template)
+		}
+	}
+	return
+}
+
+// GetConfigTemplates returns the receiver's templates whose auth accepts the request. It
+// fails with ErrUnknownReceiver when no template is configured for the receiver, and with
+// ErrUnauthorized, wrapping the first auth error, when none of them authenticated.
+func (c *Config) GetConfigTemplates(receiver string, r *http.Request, payload []byte) (templates []types.Template, err error) {
+	known := false
+	var authErr error
 	for _, template := range c.templateConfigs {
 		if template.Receiver != receiver {
 			continue
 		}
+		known = true
 
-		if !c.auth.ApplyFlow(template.Auth, r, payload) {
-			log.Printf("[AUTH] failed for receiver: %s with flow: %s", receiver, template.Auth.Flow)
+		if err = c.auth.ApplyFlow(template.Auth, r, payload); err != nil {
+			log.Printf("[AUTH] failed for receiver: %s with flow: %s reason: %s: %v", receiver, template.Auth.Name(), auth.Reason(err), err)
+			if authErr == nil {
+				authErr = err
+			}
 			continue
 		}
 
+		log.Printf("[AUTH] passed for receiver: %s with flow: %s", receiver, template.Auth.Name())
 		templates = append(templates, template)
 	}
-	return
+
+	if !known {
+		log.Printf("[AUTH] unknown receiver: %s", receiver)
+		metrics.Rejections.Add("unknown_receiver", 1)
+		return nil, fmt.Errorf("%w: %s", ErrUnknownReceiver, receiver)
+	}
+
+	if len(templates) == 0 {
+		metrics.Rejections.Add("auth_"+auth.Reason(authErr), 1)
+		return nil, fmt.Errorf("%w: %w", ErrUnauthorized, authErr)
+	}
+
+	return templates, nil
 }
@@ -2,6 +2,7 @@ package config_test
 
 import (
 	"bytes"
+	"errors"
 	"github.com/AdamShannag/hookah/internal/auth"
 	"github.com/AdamShannag/hookah/internal/config"
 	"github.com/AdamShannag/hookah/internal/types"
@@ -15,7 +16,8 @@ func TestNewConfig(t *testing.T) {
 
 	cfg := config.New(tmpls, tmplMap, auth.NewDefault())
 
-	if len(cfg.GetConfigTemplates("discord", httptest.NewRequest("POST", "/", nil), nil)) != 1 {
+	result, _ := cfg.GetConfigTemplates("discord", httptest.NewRequest("POST", "/", nil), nil)
+	if len(result) != 1 {
 		t.Error("expected one template to match")
 	}
 }
@@ -46,8 +48,11 @@ func TestGetConfigTemplates_AuthFilter(t *testing.T) {
 
 	t.Run("auth passes", func(t *testing.T) {
 		cfg := config.New(templates, nil, auth.NewDefault())
-		result := cfg.GetConfigTemplates("slack", req, []byte("test"))
+		result, err := cfg.GetConfigTemplates("slack", req, []byte("test"))
 
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
 		if len(result) != 2 {
 			t.Errorf("expected 2 templates to pass auth, got %d", len(result))
 		}
@@ -58,8 +63,11 @@ func TestGetConfigTemplates_AuthFilter(t *testing.T) {
 			{Receiver: "slack", Auth: types.Auth{Flow: "no flow"}},
 		}, nil, auth.NewDefault())
 
-		result := cfg.GetConfigTemplates("slack", req, []byte("test"))
+		result, err := cfg.GetConfigTemplates("slack", req, []byte("test"))
 
+		if !errors.Is(err, config.ErrUnauthorized) || !errors.Is(err, auth.ErrUnknownFlow) {
+			t.Errorf("expected unauthorized unknown flow error, got %v", err)
+		}
 		if len(result) != 0 {
 			t.Errorf("expected 0 templates to pass auth, got %d", len(result))
 		}
@@ -67,8 +75,11 @@ func TestGetConfigTemplates_AuthFilter(t *testing.T) {
 
 	t.Run("receiver mismatch", func(t *testing.T) {
 		cfg := config.New(templates, nil, auth.NewDefault())
-		result := cfg.GetConfigTemplates("discord", req, []byte("test"))
+		result, err := cfg.GetConfigTemplates("discord", req, []byte("test"))
 
+		if !errors.Is(err, config.ErrUnknownReceiver) {
+			t.Errorf("expected unknown receiver error, got %v", err)
+		}
 		if len(result) != 0 {
 			t.Errorf("expected 0 templates for mismatched receiver, got %d", len(result))
 		}
@@ -0,0 +1,61 @@
+package decode
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"mime"
+)
+
+// Batch decodes a payload that may carry several events: a top-level JSON array or an
+// NDJSON stream yields one map per item, while any other payload is decoded by Body and
+// returned as a single item. Every item must be a JSON object.
+func Batch(contentType string, payload []byte, jsonFields []string) ([]map[string]any, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	switch {
+	case mediaType == "application/x-ndjson" || mediaType == "application/jsonl" || mediaType == "application/x-jsonlines":
+		return fromNDJSON(payload)
+	case (mediaType == "" || mediaType == "application/json") && bytes.HasPrefix(bytes.TrimSpace(payload), []byte("[")):
+		var items []map[string]any
+		if err := JSON(payload, &items); err != nil {
+			return nil, fmt.Errorf("invalid JSON array body: %w", err)
+		}
+		for i, item := range items {
+			if item == nil {
+				return nil, fmt.Errorf("invalid JSON array body at item %d: %w", i+1, errNotObject)
+			}
+		}
+		return items, nil
+	default:
+		body, err := Body(contentType, payload, jsonFields)
+		if err != nil {
+			return nil, err
+		}
+		return []map[string]any{body}, nil
+	}
+}
+
+func fromNDJSON(payload []byte) ([]map[string]any, error) {
+	var items []map[string]any
+
+	scanner := bufio.NewScanner(bytes.NewReader(payload))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(payload)+1)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		item, err := object(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid NDJSON body at line %d: %w", line, err)
+		}
+		items = append(items, item)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("invalid NDJSON body: %w", err)
+	}
+	return items, nil
+}
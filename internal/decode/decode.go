@@ -0,0 +1,134 @@
+package decode
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const maxMultipartMemory = 10 << 20
+
+var errNotObject = errors.New("expected a JSON object")
+
+// Body decodes a request payload into a map according to its content type.
+//
+// JSON is the default for unknown or missing content types. XML is mapped as described
+// on fromXML. Form and multipart fields
+// become keys whose value is a string, or a list of strings when the field repeats;
+// fields named in jsonFields are parsed as JSON instead (e.g. Slack's "payload").
+// Multipart file parts are described by their filename, content_type and size.
+func Body(contentType string, payload []byte, jsonFields []string) (map[string]any, error) {
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(payload))
+		if err != nil {
+			return nil, fmt.Errorf("invalid form body: %w", err)
+		}
+		return fromValues(values, nil, jsonFields)
+	case "multipart/form-data":
+		form, err := multipart.NewReader(bytes.NewReader(payload), params["boundary"]).ReadForm(maxMultipartMemory)
+		if err != nil {
+			return nil, fmt.Errorf("invalid multipart body: %w", err)
+		}
+		defer form.RemoveAll()
+		return fromValues(form.Value, form.File, jsonFields)
+	case "application/xml", "text/xml":
+		return decodeXML(payload)
+	default:
+		if strings.HasSuffix(mediaType, "+xml") {
+			return decodeXML(payload)
+		}
+
+		body, err := object(payload)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON body: %w", err)
+		}
+		return body, nil
+	}
+}
+
+// JSON is json.Unmarshal with numbers decoded as json.Number, so 64-bit IDs keep every
+// digit instead of being rounded through float64.
+func JSON(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return errors.New("invalid character after top-level value")
+	}
+	return nil
+}
+
+// object decodes a JSON object, rejecting null and any other non-object value.
+func object(data []byte) (map[string]any, error) {
+	var body map[string]any
+	if err := JSON(data, &body); err != nil {
+		return nil, err
+	}
+	if body == nil {
+		return nil, errNotObject
+	}
+	return body, nil
+}
+
+func decodeXML(payload []byte) (map[string]any, error) {
+	body, err := fromXML(payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid XML body: %w", err)
+	}
+	return body, nil
+}
+
+func fromValues(values map[string][]string, files map[string][]*multipart.FileHeader, jsonFields []string) (map[string]any, error) {
+	body := make(map[string]any, len(values)+len(files))
+
+	for key, list := range values {
+		items := make([]any, 0, len(list))
+		for _, value := range list {
+			if !slices.Contains(jsonFields, key) {
+				items = append(items, value)
+				continue
+			}
+
+			var parsed any
+			if err := JSON([]byte(value), &parsed); err != nil {
+				return nil, fmt.Errorf("field '%s' is not valid JSON: %w", key, err)
+			}
+			items = append(items, parsed)
+		}
+		body[key] = single(items)
+	}
+
+	for key, headers := range files {
+		items := make([]any, 0, len(headers))
+		for _, header := range headers {
+			items = append(items, map[string]any{
+				"filename":     header.Filename,
+				"content_type": header.Header.Get("Content-Type"),
+				"size":         json.Number(strconv.FormatInt(header.Size, 10)),
+			})
+		}
+		body[key] = single(items)
+	}
+
+	return body, nil
+}
+
+func single(items []any) any {
+	if len(items) == 1 {
+		return items[0]
+	}
+	return items
+}
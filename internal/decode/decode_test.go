@@ -0,0 +1,247 @@
+package decode_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/AdamShannag/hookah/internal/decode"
+	"mime/multipart"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBody(t *testing.T) {
+	var multipartBody bytes.Buffer
+	writer := multipart.NewWriter(&multipartBody)
+	_ = writer.WriteField("event", "build")
+	_ = writer.WriteField("meta", `{"job":"nightly"}`)
+	file, _ := writer.CreateFormFile("log", "build.log")
+	_, _ = file.Write([]byte("ok"))
+	_ = writer.Close()
+
+	tests := []struct {
+		name        string
+		contentType string
+		payload     string
+		jsonFields  []string
+		want        map[string]any
+		wantErr     bool
+	}{
+		{
+			name:        "JSON",
+			contentType: "application/json; charset=utf-8",
+			payload:     `{"event":"push"}`,
+			want:        map[string]any{"event": "push"},
+		},
+		{
+			name:    "Missing content type defaults to JSON",
+			payload: `{"event":"push"}`,
+			want:    map[string]any{"event": "push"},
+		},
+		{
+			name:        "Large integers keep precision",
+			contentType: "application/json",
+			payload:     `{"id":1234567890123456789,"ratio":0.5}`,
+			want:        map[string]any{"id": json.Number("1234567890123456789"), "ratio": json.Number("0.5")},
+		},
+		{
+			name:        "Trailing data",
+			contentType: "application/json",
+			payload:     `{"id":1} {"id":2}`,
+			wantErr:     true,
+		},
+		{
+			name:        "Invalid JSON",
+			contentType: "application/json",
+			payload:     `event=push`,
+			wantErr:     true,
+		},
+		{
+			name:        "Form fields",
+			contentType: "application/x-www-form-urlencoded",
+			payload:     `command=%2Fdeploy&text=prod&label=a&label=b`,
+			want:        map[string]any{"command": "/deploy", "text": "prod", "label": []any{"a", "b"}},
+		},
+		{
+			name:        "Form JSON field",
+			contentType: "application/x-www-form-urlencoded",
+			payload:     `payload=%7B%22type%22%3A%22block_actions%22%7D`,
+			jsonFields:  []string{"payload"},
+			want:        map[string]any{"payload": map[string]any{"type": "block_actions"}},
+		},
+		{
+			name:        "Form invalid JSON field",
+			contentType: "application/x-www-form-urlencoded",
+			payload:     `payload=not-json`,
+			jsonFields:  []string{"payload"},
+			wantErr:     true,
+		},
+		{
+			name:        "Multipart",
+			contentType: writer.FormDataContentType(),
+			payload:     multipartBody.String(),
+			jsonFields:  []string{"meta"},
+			want: map[string]any{
+				"event": "build",
+				"meta":  map[string]any{"job": "nightly"},
+				"log":   map[string]any{"filename": "build.log", "content_type": "application/octet-stream", "size": json.Number("2")},
+			},
+		},
+		{
+			name:        "Multipart without boundary",
+			contentType: "multipart/form-data",
+			payload:     multipartBody.String(),
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decode.Body(tt.contentType, []byte(tt.payload), tt.jsonFields)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Body() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Body() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBody_XML(t *testing.T) {
+	payload := `<?xml version="1.0" encoding="UTF-8"?>
+<build xmlns="urn:jenkins" id="42" result="SUCCESS">
+	<name>nightly</name>
+	<changeSet>
+		<item author="alice"><msg>fix tests</msg></item>
+		<item author="bob"><msg>bump deps</msg></item>
+	</changeSet>
+	<url>https://ci/job/42</url>
+	<note lang="en">first <b>green</b> run</note>
+	<empty/>
+</build>`
+
+	want := map[string]any{
+		"build": map[string]any{
+			"@id":     "42",
+			"@result": "SUCCESS",
+			"name":    "nightly",
+			"changeSet": map[string]any{
+				"item": []any{
+					map[string]any{"@author": "alice", "msg": "fix tests"},
+					map[string]any{"@author": "bob", "msg": "bump deps"},
+				},
+			},
+			"url":   "https://ci/job/42",
+			"note":  map[string]any{"@lang": "en", "b": "green", "#text": "first  run"},
+			"empty": "",
+		},
+	}
+
+	for _, contentType := range []string{"application/xml", "text/xml; charset=utf-8", "application/atom+xml"} {
+		t.Run(contentType, func(t *testing.T) {
+			got, err := decode.Body(contentType, []byte(payload), nil)
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Body() = %#v, want %#v", got, want)
+			}
+		})
+	}
+
+	nested := func(depth int) string {
+		return strings.Repeat("<a>", depth) + "x" + strings.Repeat("</a>", depth)
+	}
+	if _, err := decode.Body("application/xml", []byte(nested(1000)), nil); err != nil {
+		t.Errorf("expected 1000 levels to decode, got: %v", err)
+	}
+
+	for _, invalid := range []string{"", "<build><name>x</build>", "just text", nested(1001), nested(1_000_000)} {
+		if _, err := decode.Body("application/xml", []byte(invalid), nil); err == nil {
+			t.Errorf("expected error for %.40q", invalid)
+		}
+	}
+}
+
+func TestBatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		payload     string
+		want        []map[string]any
+		wantErr     bool
+	}{
+		{
+			name:        "JSON array",
+			contentType: "application/json",
+			payload:     ` [{"id":"a"},{"id":"b"}]`,
+			want:        []map[string]any{{"id": "a"}, {"id": "b"}},
+		},
+		{
+			name:    "Empty array",
+			payload: `[]`,
+			want:    []map[string]any{},
+		},
+		{
+			name:        "Single object",
+			contentType: "application/json",
+			payload:     `{"id":"a"}`,
+			want:        []map[string]any{{"id": "a"}},
+		},
+		{
+			name:        "NDJSON",
+			contentType: "application/x-ndjson",
+			payload:     "{\"id\":\"a\"}\n\n{\"id\":\"b\"}\r\n",
+			want:        []map[string]any{{"id": "a"}, {"id": "b"}},
+		},
+		{
+			name:        "Form stays single",
+			contentType: "application/x-www-form-urlencoded",
+			payload:     `id=a`,
+			want:        []map[string]any{{"id": "a"}},
+		},
+		{
+			name:        "Array of scalars",
+			contentType: "application/json",
+			payload:     `[1, 2]`,
+			wantErr:     true,
+		},
+		{
+			name:        "Null array item",
+			contentType: "application/json",
+			payload:     `[{"id":"a"}, null]`,
+			wantErr:     true,
+		},
+		{
+			name:        "Null NDJSON line",
+			contentType: "application/x-ndjson",
+			payload:     "{\"id\":\"a\"}\nnull",
+			wantErr:     true,
+		},
+		{
+			name:        "Null body",
+			contentType: "application/json",
+			payload:     `null`,
+			wantErr:     true,
+		},
+		{
+			name:        "Invalid NDJSON line",
+			contentType: "application/x-ndjson",
+			payload:     "{\"id\":\"a\"}\nnot json",
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decode.Batch(tt.contentType, []byte(tt.payload), nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Batch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Batch() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
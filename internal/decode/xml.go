@@ -0,0 +1,101 @@
+package decode
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// fromXML converts an XML document into the same map shape as a JSON body:
+//
+//   - the root element is the single top-level key: <build> → {"build": ...}
+//   - attributes become "@name" keys: <build id="7"> → {"@id": "7"}
+//   - an element with only text becomes a string: <status>ok</status> → "ok"
+//   - text next to attributes or children is kept under "#text"
+//   - repeated sibling elements become a list, in document order; a single occurrence
+//     stays a plain value, so index paths like items[0] only work once it repeats
+//   - namespace prefixes are dropped and all values are strings
+//   - elements nested deeper than maxXMLDepth are rejected
+func fromXML(payload []byte) (map[string]any, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(payload))
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("no root element")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if start, ok := token.(xml.StartElement); ok {
+			value, elemErr := xmlElement(decoder, start, 1)
+			if elemErr != nil {
+				return nil, elemErr
+			}
+			return map[string]any{start.Name.Local: value}, nil
+		}
+	}
+}
+
+// maxXMLDepth bounds the recursion in xmlElement, matching the nesting limit of
+// encoding/json and encoding/xml, so a deeply nested body cannot overflow the stack.
+const maxXMLDepth = 1000
+
+func xmlElement(decoder *xml.Decoder, start xml.StartElement, depth int) (any, error) {
+	if depth > maxXMLDepth {
+		return nil, fmt.Errorf("element '%s': exceeded max depth of %d", start.Name.Local, maxXMLDepth)
+	}
+
+	node := make(map[string]any)
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+			continue
+		}
+		node["@"+attr.Name.Local] = attr.Value
+	}
+
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("element '%s': %w", start.Name.Local, err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			child, childErr := xmlElement(decoder, t, depth+1)
+			if childErr != nil {
+				return nil, childErr
+			}
+			addChild(node, t.Name.Local, child)
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			content := strings.TrimSpace(text.String())
+			if len(node) == 0 {
+				return content, nil
+			}
+			if content != "" {
+				node["#text"] = content
+			}
+			return node, nil
+		}
+	}
+}
+
+func addChild(node map[string]any, name string, child any) {
+	existing, ok := node[name]
+	if !ok {
+		node[name] = child
+		return
+	}
+	if list, isList := existing.([]any); isList {
+		node[name] = append(list, child)
+		return
+	}
+	node[name] = []any{existing, child}
+}
@@ -0,0 +1,31 @@
+package flow
+
+import (
+	"context"
+	"net/http"
+)
+
+type claimsKey struct{}
+
+type claimsHolder struct {
+	claims map[string]any
+}
+
+// WithClaims returns a request that flows can record verified token claims on.
+func WithClaims(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), claimsKey{}, &claimsHolder{}))
+}
+
+// Claims returns the claims recorded by a flow while authenticating r, if any.
+func Claims(r *http.Request) map[string]any {
+	if holder, ok := r.Context().Value(claimsKey{}).(*claimsHolder); ok {
+		return holder.claims
+	}
+	return nil
+}
+
+func setClaims(r *http.Request, claims map[string]any) {
+	if holder, ok := r.Context().Value(claimsKey{}).(*claimsHolder); ok {
+		holder.claims = claims
+	}
+}
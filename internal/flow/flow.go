@@ -5,38 +5,75 @@ import (
 	"crypto/sha256"
 	"crypto/sha512"
 	"crypto/subtle"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/AdamShannag/hookah/internal/types"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
-type Func func(auth types.Auth, r *http.Request, payload []byte) bool
+const defaultTolerance = 5 * time.Minute
 
-func None(_ types.Auth, _ *http.Request, _ []byte) bool {
-	return true
+var (
+	ErrMissingHeader  = errors.New("missing header")
+	ErrBadSignature   = errors.New("bad signature")
+	ErrStaleTimestamp = errors.New("timestamp invalid or outside tolerance")
+	ErrInvalidToken   = errors.New("invalid token")
+	ErrForbidden      = errors.New("client not allowed")
+	ErrMisconfigured  = errors.New("flow misconfigured")
+)
+
+// Func authenticates a request, returning nil on success or an error wrapping one of
+// the Err* reasons above.
+type Func func(auth types.Auth, r *http.Request, payload []byte) error
+
+func None(_ types.Auth, _ *http.Request, _ []byte) error {
+	return nil
 }
 
-func BasicAuth(auth types.Auth, r *http.Request, _ []byte) bool {
+func BasicAuth(auth types.Auth, r *http.Request, _ []byte) error {
 	username, password, ok := r.BasicAuth()
-	return ok && auth.Secret == fmt.Sprintf("%s:%s", username, password)
+	if !ok {
+		return fmt.Errorf("%w: Authorization", ErrMissingHeader)
+	}
+	if auth.Secret != fmt.Sprintf("%s:%s", username, password) {
+		return fmt.Errorf("%w: credentials do not match", ErrBadSignature)
+	}
+	return nil
 }
 
-func PlainSecret(auth types.Auth, r *http.Request, _ []byte) bool {
-	return auth.Secret == r.Header.Get(auth.HeaderSecretKey)
+func PlainSecret(auth types.Auth, r *http.Request, _ []byte) error {
+	secret := r.Header.Get(auth.HeaderSecretKey)
+	if secret == "" && auth.Secret != "" {
+		return fmt.Errorf("%w: %s", ErrMissingHeader, auth.HeaderSecretKey)
+	}
+	if auth.Secret != secret {
+		return fmt.Errorf("%w: secret does not match", ErrBadSignature)
+	}
+	return nil
 }
 
-func Gitlab(auth types.Auth, r *http.Request, _ []byte) bool {
+func Gitlab(auth types.Auth, r *http.Request, _ []byte) error {
+	token := r.Header.Get(auth.HeaderSecretKey)
+	if token == "" && auth.Secret != "" {
+		return fmt.Errorf("%w: %s", ErrMissingHeader, auth.HeaderSecretKey)
+	}
 	expected := sha512.Sum512([]byte(auth.Secret))
-	actual := sha512.Sum512([]byte(r.Header.Get(auth.HeaderSecretKey)))
-	return subtle.ConstantTimeCompare(actual[:], expected[:]) == 1
+	actual := sha512.Sum512([]byte(token))
+	if subtle.ConstantTimeCompare(actual[:], expected[:]) != 1 {
+		return fmt.Errorf("%w: token does not match", ErrBadSignature)
+	}
+	return nil
 }
 
-func Github(auth types.Auth, r *http.Request, payload []byte) bool {
+func Github(auth types.Auth, r *http.Request, payload []byte) error {
 	signature := r.Header.Get(auth.HeaderSecretKey)
 	if signature == "" {
-		return false
+		return fmt.Errorf("%w: %s", ErrMissingHeader, auth.HeaderSecretKey)
 	}
 	signature = strings.TrimPrefix(signature, "sha256=")
 
@@ -44,5 +81,150 @@ func Github(auth types.Auth, r *http.Request, payload []byte) bool {
 	_, _ = mac.Write(payload)
 	expectedMAC := hex.EncodeToString(mac.Sum(nil))
 
-	return hmac.Equal([]byte(signature), []byte(expectedMAC))
+	return compareMAC(signature, expectedMAC)
+}
+
+func Slack(auth types.Auth, r *http.Request, payload []byte) error {
+	timestamp, err := requireHeader(r, "X-Slack-Request-Timestamp")
+	if err != nil {
+		return err
+	}
+	signature, err := requireHeader(r, headerKey(auth, "X-Slack-Signature"))
+	if err != nil {
+		return err
+	}
+	if err = withinTolerance(auth, timestamp); err != nil {
+		return err
+	}
+
+	mac := hmac.New(sha256.New, []byte(auth.Secret))
+	_, _ = mac.Write([]byte("v0:" + timestamp + ":"))
+	_, _ = mac.Write(payload)
+	expectedMAC := "v0=" + hex.EncodeToString(mac.Sum(nil))
+
+	return compareMAC(signature, expectedMAC)
+}
+
+func Stripe(auth types.Auth, r *http.Request, payload []byte) error {
+	header, err := requireHeader(r, headerKey(auth, "Stripe-Signature"))
+	if err != nil {
+		return err
+	}
+
+	var timestamp string
+	var signatures []string
+	for _, pair := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return fmt.Errorf("%w: no timestamp or v1 signature", ErrBadSignature)
+	}
+	if err = withinTolerance(auth, timestamp); err != nil {
+		return err
+	}
+
+	mac := hmac.New(sha256.New, []byte(auth.Secret))
+	_, _ = mac.Write([]byte(timestamp + "."))
+	_, _ = mac.Write(payload)
+	expectedMAC := hex.EncodeToString(mac.Sum(nil))
+
+	for _, signature := range signatures {
+		if compareMAC(signature, expectedMAC) == nil {
+			return nil
+		}
+	}
+	return ErrBadSignature
+}
+
+func StandardWebhooks(auth types.Auth, r *http.Request, payload []byte) error {
+	id, err := requireHeader(r, "Webhook-Id")
+	if err != nil {
+		return err
+	}
+	timestamp, err := requireHeader(r, "Webhook-Timestamp")
+	if err != nil {
+		return err
+	}
+	signatures, err := requireHeader(r, headerKey(auth, "Webhook-Signature"))
+	if err != nil {
+		return err
+	}
+	if err = withinTolerance(auth, timestamp); err != nil {
+		return err
+	}
+
+	secret, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(auth.Secret, "whsec_"))
+	if err != nil {
+		return fmt.Errorf("%w: secret is not base64: %v", ErrMisconfigured, err)
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte(id + "." + timestamp + "."))
+	_, _ = mac.Write(payload)
+	expectedMAC := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	for _, versioned := range strings.Fields(signatures) {
+		version, signature, ok := strings.Cut(versioned, ",")
+		if ok && version == "v1" && compareMAC(signature, expectedMAC) == nil {
+			return nil
+		}
+	}
+	return ErrBadSignature
+}
+
+func headerKey(auth types.Auth, fallback string) string {
+	if auth.HeaderSecretKey != "" {
+		return auth.HeaderSecretKey
+	}
+	return fallback
+}
+
+func requireHeader(r *http.Request, key string) (string, error) {
+	value := r.Header.Get(key)
+	if value == "" {
+		return "", fmt.Errorf("%w: %s", ErrMissingHeader, key)
+	}
+	return value, nil
+}
+
+func compareMAC(signature, expectedMAC string) error {
+	if !hmac.Equal([]byte(signature), []byte(expectedMAC)) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// withinTolerance checks that the unix timestamp is no further from now than the
+// configured tolerance, in either direction.
+func withinTolerance(auth types.Auth, timestamp string) error {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrStaleTimestamp, timestamp)
+	}
+
+	tolerance := defaultTolerance
+	if auth.Tolerance != "" {
+		if tolerance, err = time.ParseDuration(auth.Tolerance); err != nil {
+			return fmt.Errorf("%w: invalid tolerance: %v", ErrMisconfigured, err)
+		}
+	}
+
+	age := time.Since(time.Unix(seconds, 0))
+	if age < 0 {
+		age = -age
+	}
+
+	if age > tolerance {
+		return fmt.Errorf("%w: %s old", ErrStaleTimestamp, age.Round(time.Second))
+	}
+	return nil
 }
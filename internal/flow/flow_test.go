@@ -4,17 +4,22 @@ import (
 	"bytes"
 	"crypto/hmac"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"github.com/AdamShannag/hookah/internal/flow"
 	"github.com/AdamShannag/hookah/internal/types"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 )
 
 func TestNone(t *testing.T) {
 	auth := types.Auth{}
 	req := httptest.NewRequest("POST", "/", nil)
-	if !flow.None(auth, req, nil) {
+	if flow.None(auth, req, nil) != nil {
 		t.Error("None should always return true")
 	}
 }
@@ -24,12 +29,12 @@ func TestBasicAuth(t *testing.T) {
 	req := httptest.NewRequest("POST", "/", nil)
 	req.SetBasicAuth("user", "pass")
 
-	if !flow.BasicAuth(auth, req, nil) {
+	if flow.BasicAuth(auth, req, nil) != nil {
 		t.Error("BasicAuth should succeed with matching credentials")
 	}
 
 	req.SetBasicAuth("user", "wrong")
-	if flow.BasicAuth(auth, req, nil) {
+	if flow.BasicAuth(auth, req, nil) == nil {
 		t.Error("BasicAuth should fail with incorrect credentials")
 	}
 }
@@ -42,12 +47,12 @@ func TestPlainSecret(t *testing.T) {
 	req := httptest.NewRequest("POST", "/", nil)
 	req.Header.Set("X-Secret-Key", "my-secret")
 
-	if !flow.PlainSecret(auth, req, nil) {
+	if flow.PlainSecret(auth, req, nil) != nil {
 		t.Error("PlainSecret should succeed with correct secret")
 	}
 
 	req.Header.Set("X-Secret-Key", "wrong-secret")
-	if flow.PlainSecret(auth, req, nil) {
+	if flow.PlainSecret(auth, req, nil) == nil {
 		t.Error("PlainSecret should fail with incorrect secret")
 	}
 }
@@ -61,12 +66,12 @@ func TestGitlab(t *testing.T) {
 	req := httptest.NewRequest("POST", "/", nil)
 	req.Header.Set("X-Gitlab-Token", secret)
 
-	if !flow.Gitlab(auth, req, nil) {
+	if flow.Gitlab(auth, req, nil) != nil {
 		t.Error("Gitlab should succeed with correct hash")
 	}
 
 	req.Header.Set("X-Gitlab-Token", "invalid")
-	if flow.Gitlab(auth, req, nil) {
+	if flow.Gitlab(auth, req, nil) == nil {
 		t.Error("Gitlab should fail with incorrect hash")
 	}
 }
@@ -84,12 +89,167 @@ func TestGithub(t *testing.T) {
 	req := httptest.NewRequest("POST", "/", bytes.NewReader(payload))
 	req.Header.Set("X-Hub-Signature-256", "sha256="+signature)
 
-	if !flow.Github(auth, req, payload) {
+	if flow.Github(auth, req, payload) != nil {
 		t.Error("Github should succeed with correct signature")
 	}
 
 	req.Header.Set("X-Hub-Signature-256", "sha256=invalidsignature")
-	if flow.Github(auth, req, payload) {
+	if flow.Github(auth, req, payload) == nil {
 		t.Error("Github should fail with incorrect signature")
 	}
 }
+
+func TestSlack(t *testing.T) {
+	secret := "slack-secret"
+	payload := []byte(`token=abc&command=%2Fdeploy`)
+
+	sign := func(timestamp string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(fmt.Sprintf("v0:%s:%s", timestamp, payload)))
+		return "v0=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	tests := []struct {
+		name      string
+		auth      types.Auth
+		timestamp string
+		signature func(timestamp string) string
+		expected  bool
+	}{
+		{"ValidSignature", types.Auth{Secret: secret}, strconv.FormatInt(time.Now().Unix(), 10), sign, true},
+		{"WrongSecret", types.Auth{Secret: "other"}, strconv.FormatInt(time.Now().Unix(), 10), sign, false},
+		{"StaleTimestamp", types.Auth{Secret: secret}, strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10), sign, false},
+		{"CustomTolerance", types.Auth{Secret: secret, Tolerance: "15m"}, strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10), sign, true},
+		{"InvalidTimestamp", types.Auth{Secret: secret}, "not-a-number", sign, false},
+		{"MissingSignature", types.Auth{Secret: secret}, strconv.FormatInt(time.Now().Unix(), 10), func(string) string { return "" }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/", bytes.NewReader(payload))
+			req.Header.Set("X-Slack-Request-Timestamp", tt.timestamp)
+			req.Header.Set("X-Slack-Signature", tt.signature(tt.timestamp))
+
+			if result := flow.Slack(tt.auth, req, payload) == nil; result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestStripe(t *testing.T) {
+	secret := "whsec_stripe"
+	payload := []byte(`{"id":"evt_1","type":"charge.succeeded"}`)
+
+	sign := func(key, timestamp string) string {
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write([]byte(timestamp + "." + string(payload)))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name     string
+		auth     types.Auth
+		header   string
+		expected bool
+	}{
+		{"ValidSignature", types.Auth{Secret: secret}, "t=" + now + ",v1=" + sign(secret, now), true},
+		{"RolledSecret", types.Auth{Secret: secret}, "t=" + now + ",v1=" + sign("whsec_old", now) + ",v1=" + sign(secret, now), true},
+		{"OnlyV0Signature", types.Auth{Secret: secret}, "t=" + now + ",v0=" + sign(secret, now), false},
+		{"WrongSignature", types.Auth{Secret: secret}, "t=" + now + ",v1=" + sign("whsec_other", now), false},
+		{"StaleTimestamp", types.Auth{Secret: secret}, "t=" + stale + ",v1=" + sign(secret, stale), false},
+		{"CustomTolerance", types.Auth{Secret: secret, Tolerance: "2h"}, "t=" + stale + ",v1=" + sign(secret, stale), true},
+		{"MissingTimestamp", types.Auth{Secret: secret}, "v1=" + sign(secret, now), false},
+		{"MissingHeader", types.Auth{Secret: secret}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/", bytes.NewReader(payload))
+			req.Header.Set("Stripe-Signature", tt.header)
+
+			if result := flow.Stripe(tt.auth, req, payload) == nil; result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestStandardWebhooks(t *testing.T) {
+	key := []byte("standard-webhooks-secret")
+	secret := "whsec_" + base64.StdEncoding.EncodeToString(key)
+	payload := []byte(`{"type":"user.created"}`)
+
+	sign := func(key []byte, id, timestamp string) string {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(id + "." + timestamp + "." + string(payload)))
+		return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name      string
+		auth      types.Auth
+		id        string
+		timestamp string
+		signature string
+		expected  bool
+	}{
+		{"ValidSignature", types.Auth{Secret: secret}, "msg_1", now, "v1," + sign(key, "msg_1", now), true},
+		{"MultipleSignatures", types.Auth{Secret: secret}, "msg_1", now, "v1,bm9wZQ== v1," + sign(key, "msg_1", now), true},
+		{"UnknownVersion", types.Auth{Secret: secret}, "msg_1", now, "v2," + sign(key, "msg_1", now), false},
+		{"TamperedID", types.Auth{Secret: secret}, "msg_2", now, "v1," + sign(key, "msg_1", now), false},
+		{"StaleTimestamp", types.Auth{Secret: secret}, "msg_1", stale, "v1," + sign(key, "msg_1", stale), false},
+		{"InvalidSecret", types.Auth{Secret: "whsec_%%%"}, "msg_1", now, "v1," + sign(key, "msg_1", now), false},
+		{"MissingID", types.Auth{Secret: secret}, "", now, "v1," + sign(key, "", now), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/", bytes.NewReader(payload))
+			req.Header.Set("Webhook-Id", tt.id)
+			req.Header.Set("Webhook-Timestamp", tt.timestamp)
+			req.Header.Set("Webhook-Signature", tt.signature)
+
+			if result := flow.StandardWebhooks(tt.auth, req, payload) == nil; result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestFailureReasons(t *testing.T) {
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name     string
+		flowFunc flow.Func
+		auth     types.Auth
+		headers  map[string]string
+		expected error
+	}{
+		{"GithubMissingHeader", flow.Github, types.Auth{HeaderSecretKey: "X-Hub-Signature-256"}, nil, flow.ErrMissingHeader},
+		{"GithubBadSignature", flow.Github, types.Auth{HeaderSecretKey: "X-Hub-Signature-256"}, map[string]string{"X-Hub-Signature-256": "sha256=00"}, flow.ErrBadSignature},
+		{"SlackStaleTimestamp", flow.Slack, types.Auth{}, map[string]string{"X-Slack-Request-Timestamp": stale, "X-Slack-Signature": "v0=00"}, flow.ErrStaleTimestamp},
+		{"SlackBadTolerance", flow.Slack, types.Auth{Tolerance: "soon"}, map[string]string{"X-Slack-Request-Timestamp": stale, "X-Slack-Signature": "v0=00"}, flow.ErrMisconfigured},
+		{"BasicAuthMissing", flow.BasicAuth, types.Auth{Secret: "user:pass"}, nil, flow.ErrMissingHeader},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			if err := tt.flowFunc(tt.auth, req, nil); !errors.Is(err, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
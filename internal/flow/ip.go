@@ -0,0 +1,158 @@
+package flow
+
+import (
+	"fmt"
+	"github.com/AdamShannag/hookah/internal/types"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// IPAllowlist accepts requests whose client address falls in auth.AllowedCIDRs. When the
+// direct peer is one of auth.TrustedProxies, the client is taken from the forwarded chain
+// in auth.ForwardedHeader: the right-most hop that is not itself a trusted proxy.
+func IPAllowlist(auth types.Auth, r *http.Request, _ []byte) error {
+	trusted, err := parsePrefixes("trusted_proxies", auth.TrustedProxies)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrMisconfigured, err)
+	}
+	allowed, err := parsePrefixes("allowed_cidrs", auth.AllowedCIDRs)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrMisconfigured, err)
+	}
+	header, err := forwardedHeader(auth)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrMisconfigured, err)
+	}
+
+	client, ok := clientAddr(r, header, trusted)
+	if !ok {
+		return fmt.Errorf("%w: unparseable client address", ErrForbidden)
+	}
+	if !containsAddr(allowed, client) {
+		return fmt.Errorf("%w: %s", ErrForbidden, client)
+	}
+	return nil
+}
+
+// ValidateIPAllowlist reports entries in allowed_cidrs or trusted_proxies that are neither
+// a CIDR nor an address, and unknown forwarded_header values, including those of nested
+// all/any policies, so a typo is caught when the config loads instead of failing every request.
+func ValidateIPAllowlist(auth types.Auth) error {
+	if _, err := parsePrefixes("allowed_cidrs", auth.AllowedCIDRs); err != nil {
+		return err
+	}
+	if _, err := parsePrefixes("trusted_proxies", auth.TrustedProxies); err != nil {
+		return err
+	}
+	if _, err := forwardedHeader(auth); err != nil {
+		return err
+	}
+	for i, sub := range auth.All {
+		if err := ValidateIPAllowlist(sub); err != nil {
+			return fmt.Errorf("all[%d]: %w", i, err)
+		}
+	}
+	for i, sub := range auth.Any {
+		if err := ValidateIPAllowlist(sub); err != nil {
+			return fmt.Errorf("any[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// forwardedHeader is the header the trusted proxies append to: "x-forwarded-for", the
+// default, or the RFC 7239 "forwarded". Only that header is read, since the client can
+// send the other one itself.
+func forwardedHeader(auth types.Auth) (string, error) {
+	switch auth.ForwardedHeader {
+	case "", "x-forwarded-for":
+		return "X-Forwarded-For", nil
+	case "forwarded":
+		return "Forwarded", nil
+	default:
+		return "", fmt.Errorf("invalid forwarded_header '%s': expected 'x-forwarded-for' or 'forwarded'", auth.ForwardedHeader)
+	}
+}
+
+func clientAddr(r *http.Request, header string, trusted []netip.Prefix) (netip.Addr, bool) {
+	remote, ok := parseAddr(r.RemoteAddr)
+	if !ok {
+		return netip.Addr{}, false
+	}
+	if !containsAddr(trusted, remote) {
+		return remote, true
+	}
+
+	chain := forwardedChain(r.Header, header)
+	for i := len(chain) - 1; i >= 0; i-- {
+		hop, hopOk := parseAddr(chain[i])
+		if !hopOk {
+			return netip.Addr{}, false
+		}
+		if i == 0 || !containsAddr(trusted, hop) {
+			return hop, true
+		}
+	}
+
+	return remote, true
+}
+
+// forwardedChain returns the hops of the named header, left-most first.
+func forwardedChain(header http.Header, name string) (chain []string) {
+	elements := strings.Split(strings.Join(header.Values(name), ","), ",")
+	if name == "Forwarded" {
+		for _, element := range elements {
+			for _, pair := range strings.Split(element, ";") {
+				key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(key, "for") {
+					chain = append(chain, strings.Trim(value, `"`))
+				}
+			}
+		}
+		return
+	}
+
+	for _, hop := range elements {
+		if hop = strings.TrimSpace(hop); hop != "" {
+			chain = append(chain, hop)
+		}
+	}
+	return
+}
+
+// parseAddr accepts "ip", "ip:port", "[ipv6]" and "[ipv6]:port".
+func parseAddr(s string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	addr, err := netip.ParseAddr(strings.Trim(s, "[]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+func parsePrefixes(field string, cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if prefix, err := netip.ParsePrefix(cidr); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+		} else if addr, ok := parseAddr(cidr); ok {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+		} else {
+			return nil, fmt.Errorf("invalid %s entry '%s': expected a CIDR or an IP address", field, cidr)
+		}
+	}
+	return prefixes, nil
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package flow_test
+
+import (
+	"errors"
+	"github.com/AdamShannag/hookah/internal/flow"
+	"github.com/AdamShannag/hookah/internal/types"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIPAllowlist(t *testing.T) {
+	auth := types.Auth{
+		AllowedCIDRs:   []string{"192.30.252.0/22", "2a0a:a440::/29", "203.0.113.7"},
+		TrustedProxies: []string{"10.0.0.0/8"},
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		expected   bool
+	}{
+		{"DirectAllowed", "192.30.252.10:443", nil, true},
+		{"DirectDenied", "198.51.100.1:443", nil, false},
+		{"SingleAddressEntry", "203.0.113.7:80", nil, true},
+		{"IPv6Allowed", "[2a0a:a440::1]:443", nil, true},
+		{"IPv4MappedIPv6", "[::ffff:192.30.252.10]:443", nil, true},
+		{"UntrustedPeerForwardedIgnored", "198.51.100.1:443", map[string]string{"X-Forwarded-For": "192.30.252.10"}, false},
+		{"TrustedProxyXFF", "10.0.0.5:443", map[string]string{"X-Forwarded-For": "192.30.252.10"}, true},
+		{"SpoofedLeftMostIgnored", "10.0.0.5:443", map[string]string{"X-Forwarded-For": "192.30.252.10, 198.51.100.1"}, false},
+		{"MultipleTrustedHops", "10.0.0.5:443", map[string]string{"X-Forwarded-For": "192.30.252.10, 10.1.2.3"}, true},
+		{"TrustedProxyWithoutHeader", "10.0.0.5:443", nil, false},
+		{"ClientForwardedIgnored", "10.0.0.5:443", map[string]string{"Forwarded": "for=192.30.252.1", "X-Forwarded-For": "203.0.113.9"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			if result := flow.IPAllowlist(auth, req, nil) == nil; result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestIPAllowlist_ForwardedHeader(t *testing.T) {
+	auth := types.Auth{
+		AllowedCIDRs:    []string{"192.30.252.0/22", "2a0a:a440::/29"},
+		TrustedProxies:  []string{"10.0.0.0/8"},
+		ForwardedHeader: "forwarded",
+	}
+
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		expected bool
+	}{
+		{"Forwarded", map[string]string{"Forwarded": `for="[2a0a:a440::1]:4711";proto=https, for=10.9.9.9`}, true},
+		{"ClientXFFIgnored", map[string]string{"Forwarded": "for=203.0.113.9", "X-Forwarded-For": "192.30.252.1"}, false},
+		{"NoFallbackToXFF", map[string]string{"X-Forwarded-For": "192.30.252.1"}, false},
+		{"Obfuscated", map[string]string{"Forwarded": "for=unknown"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/", nil)
+			req.RemoteAddr = "10.0.0.5:443"
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			if result := flow.IPAllowlist(auth, req, nil) == nil; result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestIPAllowlist_InvalidEntryRejects(t *testing.T) {
+	req := httptest.NewRequest("POST", "/", nil)
+	req.RemoteAddr = "192.30.252.10:443"
+
+	auth := types.Auth{AllowedCIDRs: []string{"192.30.252.0/22", "10.0.0.0/33"}}
+	if err := flow.IPAllowlist(auth, req, nil); !errors.Is(err, flow.ErrMisconfigured) {
+		t.Errorf("expected an invalid allowed_cidrs entry to be reported as misconfigured, got %v", err)
+	}
+}
+
+func TestValidateIPAllowlist(t *testing.T) {
+	tests := []struct {
+		name    string
+		auth    types.Auth
+		wantErr bool
+	}{
+		{"Valid", types.Auth{AllowedCIDRs: []string{"192.30.252.0/22", "203.0.113.7", "::1"}, TrustedProxies: []string{"10.0.0.0/8"}}, false},
+		{"No lists", types.Auth{Flow: "github"}, false},
+		{"Invalid allowed CIDR", types.Auth{AllowedCIDRs: []string{"192.30.252.0/33"}}, true},
+		{"Invalid trusted proxy", types.Auth{AllowedCIDRs: []string{"192.30.252.0/22"}, TrustedProxies: []string{"10.0.0.0/8 "}}, true},
+		{"Forwarded header", types.Auth{TrustedProxies: []string{"10.0.0.0/8"}, ForwardedHeader: "forwarded"}, false},
+		{"Unknown forwarded header", types.Auth{TrustedProxies: []string{"10.0.0.0/8"}, ForwardedHeader: "x-real-ip"}, true},
+		{"Invalid in nested all", types.Auth{All: []types.Auth{{Flow: "github"}, {AllowedCIDRs: []string{"proxy.internal"}}}}, true},
+		{"Invalid in nested any", types.Auth{Any: []types.Auth{{All: []types.Auth{{TrustedProxies: []string{"10.0.0/8"}}}}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := flow.ValidateIPAllowlist(tt.auth); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateIPAllowlist() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+package flow
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/AdamShannag/hookah/internal/decode"
+	"github.com/AdamShannag/hookah/internal/types"
+	"math"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JWT verifies an HS256, RS256 or ES256 bearer token against the shared secret or the
+// keys in auth.JWKSPath and, on success, records its claims on the request.
+func JWT(auth types.Auth, r *http.Request, _ []byte) error {
+	key := headerKey(auth, "Authorization")
+	token := strings.TrimSpace(r.Header.Get(key))
+	if len(token) > 7 && strings.EqualFold(token[:7], "bearer ") {
+		token = strings.TrimSpace(token[7:])
+	}
+	if token == "" {
+		return fmt.Errorf("%w: %s", ErrMissingHeader, key)
+	}
+
+	claims, err := verifyJWT(auth, token)
+	if err != nil {
+		return err
+	}
+
+	setClaims(r, claims)
+	return nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+func verifyJWT(auth types.Auth, token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidToken, err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", ErrInvalidToken, err)
+	}
+
+	if err = verifySignature(auth, header, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]any
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrInvalidToken, err)
+	}
+
+	if err = validateClaims(auth, claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func verifySignature(auth types.Auth, header jwtHeader, signed, signature []byte) error {
+	switch header.Alg {
+	case "HS256":
+		if auth.Secret == "" {
+			return fmt.Errorf("%w: no secret configured for HS256", ErrMisconfigured)
+		}
+		mac := hmac.New(sha256.New, []byte(auth.Secret))
+		_, _ = mac.Write(signed)
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return ErrBadSignature
+		}
+		return nil
+	case "RS256", "ES256":
+		keys, err := loadJWKS(auth.JWKSPath)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrMisconfigured, err)
+		}
+		digest := sha256.Sum256(signed)
+		for _, key := range keys {
+			if header.Kid != "" && key.kid != header.Kid {
+				continue
+			}
+			if verifyWithKey(header.Alg, key.public, digest[:], signature) {
+				return nil
+			}
+		}
+		return ErrBadSignature
+	default:
+		return fmt.Errorf("%w: unsupported alg '%s'", ErrInvalidToken, header.Alg)
+	}
+}
+
+func verifyWithKey(alg string, public crypto.PublicKey, digest, signature []byte) bool {
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		return alg == "RS256" && rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, signature) == nil
+	case *ecdsa.PublicKey:
+		if alg != "ES256" || key.Curve != elliptic.P256() || len(signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(key, digest, r, s)
+	default:
+		return false
+	}
+}
+
+func validateClaims(auth types.Auth, claims map[string]any) error {
+	var leeway time.Duration
+	if auth.Tolerance != "" {
+		var err error
+		if leeway, err = time.ParseDuration(auth.Tolerance); err != nil {
+			return fmt.Errorf("%w: invalid tolerance: %v", ErrMisconfigured, err)
+		}
+	}
+	now := time.Now()
+
+	if exp, ok := claims["exp"]; ok {
+		expires, isDate := numericDate(exp)
+		if !isDate || now.After(expires.Add(leeway)) {
+			return fmt.Errorf("%w: token expired", ErrInvalidToken)
+		}
+	}
+
+	if nbf, ok := claims["nbf"]; ok {
+		notBefore, isDate := numericDate(nbf)
+		if !isDate || now.Before(notBefore.Add(-leeway)) {
+			return fmt.Errorf("%w: token not yet valid", ErrInvalidToken)
+		}
+	}
+
+	if auth.Issuer != "" && claims["iss"] != auth.Issuer {
+		return fmt.Errorf("%w: issuer mismatch", ErrInvalidToken)
+	}
+
+	if auth.Audience != "" && !hasAudience(claims["aud"], auth.Audience) {
+		return fmt.Errorf("%w: audience mismatch", ErrInvalidToken)
+	}
+
+	return nil
+}
+
+// numericDate reads a JWT NumericDate, seconds since the epoch, from a decoded claim.
+func numericDate(v any) (time.Time, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	if seconds, err := n.Int64(); err == nil {
+		return time.Unix(seconds, 0), true
+	}
+	seconds, err := n.Float64()
+	if err != nil || seconds < math.MinInt64 || seconds >= math.MaxInt64 {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0), true
+}
+
+func hasAudience(aud any, expected string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == expected
+	case []any:
+		for _, item := range v {
+			if item == expected {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return decode.JSON(data, v)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type publicKey struct {
+	kid    string
+	public crypto.PublicKey
+}
+
+type cachedJWKS struct {
+	modTime time.Time
+	keys    []publicKey
+}
+
+var (
+	jwksMu    sync.Mutex
+	jwksCache = map[string]cachedJWKS{}
+)
+
+// loadJWKS reads a JWKS file, re-parsing it only when its modification time changes.
+func loadJWKS(path string) ([]publicKey, error) {
+	if path == "" {
+		return nil, errors.New("no jwks_path configured")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	jwksMu.Lock()
+	defer jwksMu.Unlock()
+
+	if cached, ok := jwksCache[path]; ok && cached.modTime.Equal(info.ModTime()) {
+		return cached.keys, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err = json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid jwks: %w", err)
+	}
+
+	keys := make([]publicKey, 0, len(set.Keys))
+	for _, k := range set.Keys {
+		public, keyErr := k.publicKey()
+		if keyErr != nil {
+			continue
+		}
+		keys = append(keys, publicKey{kid: k.Kid, public: public})
+	}
+
+	jwksCache[path] = cachedJWKS{modTime: info.ModTime(), keys: keys}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve '%s'", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type '%s'", k.Kty)
+	}
+}
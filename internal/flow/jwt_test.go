@@ -0,0 +1,149 @@
+package flow_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/AdamShannag/hookah/internal/flow"
+	"github.com/AdamShannag/hookah/internal/types"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJWT(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	jwksPath := writeJWKS(t, rsaKey, ecKey)
+
+	now := time.Now().Unix()
+	valid := map[string]any{"sub": "ci-bot", "iss": "https://issuer", "aud": []any{"hookah"}, "exp": now + 60, "nbf": now - 60}
+
+	hs256 := func(claims map[string]any) string {
+		signed := segment(map[string]any{"alg": "HS256", "typ": "JWT"}) + "." + segment(claims)
+		mac := hmac.New(sha256.New, []byte("shared"))
+		mac.Write([]byte(signed))
+		return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	}
+	rs256 := func(claims map[string]any) string {
+		signed := segment(map[string]any{"alg": "RS256", "kid": "rsa-1"}) + "." + segment(claims)
+		digest := sha256.Sum256([]byte(signed))
+		sig, _ := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+		return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+	}
+	es256 := func(claims map[string]any) string {
+		signed := segment(map[string]any{"alg": "ES256", "kid": "ec-1"}) + "." + segment(claims)
+		digest := sha256.Sum256([]byte(signed))
+		r, s, _ := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+		return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+	}
+	with := func(key string, value any) map[string]any {
+		claims := map[string]any{}
+		for k, v := range valid {
+			claims[k] = v
+		}
+		claims[key] = value
+		return claims
+	}
+
+	shared := types.Auth{Secret: "shared", Issuer: "https://issuer", Audience: "hookah"}
+	jwks := types.Auth{JWKSPath: jwksPath, Issuer: "https://issuer", Audience: "hookah"}
+
+	tests := []struct {
+		name     string
+		auth     types.Auth
+		token    string
+		expected bool
+	}{
+		{"HS256", shared, hs256(valid), true},
+		{"RS256", jwks, rs256(valid), true},
+		{"ES256", jwks, es256(valid), true},
+		{"WrongSecret", types.Auth{Secret: "other"}, hs256(valid), false},
+		{"HS256WithoutSecret", jwks, hs256(valid), false},
+		{"RS256WithoutJWKS", shared, rs256(valid), false},
+		{"Expired", shared, hs256(with("exp", now-60)), false},
+		{"ExpiredWithinLeeway", types.Auth{Secret: "shared", Tolerance: "2m"}, hs256(with("exp", now-60)), true},
+		{"NotYetValid", shared, hs256(with("nbf", now+600)), false},
+		{"FractionalExpiry", shared, hs256(with("exp", float64(now)+60.5)), true},
+		{"ExpiryNotANumber", shared, hs256(with("exp", "soon")), false},
+		{"IssuerMismatch", shared, hs256(with("iss", "https://other")), false},
+		{"AudienceMismatch", shared, hs256(with("aud", "someone-else")), false},
+		{"AlgNone", shared, segment(map[string]any{"alg": "none"}) + "." + segment(valid) + ".", false},
+		{"Malformed", shared, "not-a-token", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := flow.WithClaims(httptest.NewRequest("POST", "/", nil))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+
+			if result := flow.JWT(tt.auth, req, nil) == nil; result != tt.expected {
+				t.Fatalf("expected %v, got %v", tt.expected, result)
+			}
+
+			claims := flow.Claims(req)
+			if tt.expected && claims["sub"] != "ci-bot" {
+				t.Errorf("expected verified claims to be recorded, got %v", claims)
+			}
+			if !tt.expected && claims != nil {
+				t.Errorf("expected no claims on failure, got %v", claims)
+			}
+		})
+	}
+}
+
+func TestJWT_NumericClaimsKeepPrecision(t *testing.T) {
+	claims := map[string]any{"sub": json.Number("1234567890123456789"), "iat": json.Number("1700000000"), "exp": time.Now().Unix() + 60}
+	signed := segment(map[string]any{"alg": "HS256"}) + "." + segment(claims)
+	mac := hmac.New(sha256.New, []byte("shared"))
+	mac.Write([]byte(signed))
+
+	req := flow.WithClaims(httptest.NewRequest("POST", "/", nil))
+	req.Header.Set("Authorization", "Bearer "+signed+"."+base64.RawURLEncoding.EncodeToString(mac.Sum(nil)))
+	if err := flow.JWT(types.Auth{Secret: "shared"}, req, nil); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	got := flow.Claims(req)
+	if got["sub"] != json.Number("1234567890123456789") || got["iat"] != json.Number("1700000000") {
+		t.Errorf("expected numeric claims as json.Number, got %#v", got)
+	}
+}
+
+func segment(v any) string {
+	data, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func writeJWKS(t *testing.T, rsaKey *rsa.PrivateKey, ecKey *ecdsa.PrivateKey) string {
+	t.Helper()
+
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	coord := func(i *big.Int) string {
+		buf := make([]byte, 32)
+		return b64(i.FillBytes(buf))
+	}
+
+	data, _ := json.Marshal(map[string]any{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa-1", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": coord(ecKey.X), "y": coord(ecKey.Y)},
+	}})
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
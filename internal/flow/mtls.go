@@ -0,0 +1,57 @@
+package flow
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"github.com/AdamShannag/hookah/internal/types"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// MTLS accepts requests that presented a client certificate verified against the
+// listener's CA bundle. Each configured criterion (subject, SAN, fingerprint) must match.
+func MTLS(auth types.Auth, r *http.Request, _ []byte) error {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return fmt.Errorf("%w: no verified client certificate", ErrForbidden)
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+
+	if len(auth.ClientSubjects) > 0 && !slices.ContainsFunc(auth.ClientSubjects, func(subject string) bool {
+		return subject == cert.Subject.CommonName || subject == cert.Subject.String()
+	}) {
+		return fmt.Errorf("%w: subject %s", ErrForbidden, cert.Subject)
+	}
+
+	if len(auth.ClientSANs) > 0 && !slices.ContainsFunc(certSANs(cert), func(san string) bool {
+		return slices.Contains(auth.ClientSANs, san)
+	}) {
+		return fmt.Errorf("%w: no matching SAN", ErrForbidden)
+	}
+
+	if len(auth.ClientFingerprints) > 0 {
+		sum := sha256.Sum256(cert.Raw)
+		fingerprint := hex.EncodeToString(sum[:])
+		if !slices.ContainsFunc(auth.ClientFingerprints, func(expected string) bool {
+			return strings.EqualFold(strings.ReplaceAll(expected, ":", ""), fingerprint)
+		}) {
+			return fmt.Errorf("%w: fingerprint %s", ErrForbidden, fingerprint)
+		}
+	}
+
+	return nil
+}
+
+func certSANs(cert *x509.Certificate) []string {
+	sans := append([]string{}, cert.DNSNames...)
+	sans = append(sans, cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	return sans
+}
@@ -0,0 +1,75 @@
+package flow_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"github.com/AdamShannag/hookah/internal/flow"
+	"github.com/AdamShannag/hookah/internal/types"
+	"math/big"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMTLS(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	spiffe, _ := url.Parse("spiffe://internal/ci")
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "ci-runner", Organization: []string{"Internal"}},
+		DNSNames:     []string{"ci.internal"},
+		URIs:         []*url.URL{spiffe},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, _ := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	cert, _ := x509.ParseCertificate(der)
+	sum := sha256.Sum256(der)
+	fingerprint := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	tests := []struct {
+		name     string
+		auth     types.Auth
+		state    *tls.ConnectionState
+		expected bool
+	}{
+		{"AnyVerifiedCert", types.Auth{}, verified(cert), true},
+		{"CommonName", types.Auth{ClientSubjects: []string{"ci-runner"}}, verified(cert), true},
+		{"FullSubject", types.Auth{ClientSubjects: []string{"CN=ci-runner,O=Internal"}}, verified(cert), true},
+		{"SubjectMismatch", types.Auth{ClientSubjects: []string{"deploy-bot"}}, verified(cert), false},
+		{"DNSSAN", types.Auth{ClientSANs: []string{"ci.internal"}}, verified(cert), true},
+		{"URISAN", types.Auth{ClientSANs: []string{"spiffe://internal/ci"}}, verified(cert), true},
+		{"SANMismatch", types.Auth{ClientSANs: []string{"other.internal"}}, verified(cert), false},
+		{"Fingerprint", types.Auth{ClientFingerprints: []string{fingerprint}}, verified(cert), true},
+		{"FingerprintMismatch", types.Auth{ClientFingerprints: []string{"00"}}, verified(cert), false},
+		{"AllCriteriaMustMatch", types.Auth{ClientSubjects: []string{"ci-runner"}, ClientSANs: []string{"other.internal"}}, verified(cert), false},
+		{"UnverifiedPeer", types.Auth{}, &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}, false},
+		{"PlainHTTP", types.Auth{}, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/", nil)
+			req.TLS = tt.state
+
+			if result := flow.MTLS(tt.auth, req, nil) == nil; result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func verified(cert *x509.Certificate) *tls.ConnectionState {
+	return &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert},
+		VerifiedChains:   [][]*x509.Certificate{{cert}},
+	}
+}
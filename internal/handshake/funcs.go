@@ -0,0 +1,119 @@
+package handshake
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"github.com/AdamShannag/hookah/internal/types"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Slack answers the Events API url_verification challenge.
+func Slack(_ types.Handshake, r *http.Request, payload []byte) (*Response, bool) {
+	if r.Method != http.MethodPost {
+		return nil, false
+	}
+
+	var body struct {
+		Type      string `json:"type"`
+		Challenge string `json:"challenge"`
+	}
+	if err := json.Unmarshal(payload, &body); err != nil || body.Type != "url_verification" {
+		return nil, false
+	}
+
+	return text(http.StatusOK, body.Challenge), true
+}
+
+// Meta answers the GET hub.challenge subscription check once hub.verify_token matches.
+func Meta(cfg types.Handshake, r *http.Request, _ []byte) (*Response, bool) {
+	query := r.URL.Query()
+	if r.Method != http.MethodGet || query.Get("hub.mode") != "subscribe" || !query.Has("hub.challenge") {
+		return nil, false
+	}
+
+	token := query.Get("hub.verify_token")
+	if cfg.VerifyToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(cfg.VerifyToken)) != 1 {
+		return text(http.StatusForbidden, http.StatusText(http.StatusForbidden)), true
+	}
+
+	return text(http.StatusOK, query.Get("hub.challenge")), true
+}
+
+// Graph echoes the Microsoft Graph subscription validationToken.
+func Graph(_ types.Handshake, r *http.Request, _ []byte) (*Response, bool) {
+	query := r.URL.Query()
+	if r.Method != http.MethodPost || !query.Has("validationToken") {
+		return nil, false
+	}
+
+	return text(http.StatusOK, query.Get("validationToken")), true
+}
+
+// SNS confirms an AWS SNS SubscriptionConfirmation by visiting its SubscribeURL.
+func SNS(_ types.Handshake, r *http.Request, payload []byte) (*Response, bool) {
+	if r.Method != http.MethodPost {
+		return nil, false
+	}
+
+	var body struct {
+		Type         string `json:"Type"`
+		SubscribeURL string `json:"SubscribeURL"`
+	}
+	if err := json.Unmarshal(payload, &body); err != nil || body.Type != "SubscriptionConfirmation" {
+		return nil, false
+	}
+
+	if err := confirmSubscription(body.SubscribeURL); err != nil {
+		log.Printf("[Handshake] SNS subscription confirmation failed: %v", err)
+		return text(http.StatusBadGateway, http.StatusText(http.StatusBadGateway)), true
+	}
+
+	return text(http.StatusOK, ""), true
+}
+
+var snsClient = &http.Client{Timeout: 10 * time.Second}
+
+// confirmSubscription is a variable so tests can stub out the call to AWS.
+var confirmSubscription = confirm
+
+func confirm(subscribeURL string) error {
+	u, err := url.Parse(subscribeURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "https" || !isSNSHost(u.Hostname()) {
+		return fmt.Errorf("refusing SubscribeURL outside sns.*.amazonaws.com: %s", u.Host)
+	}
+
+	resp, err := snsClient.Get(u.String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
+
+func isSNSHost(host string) bool {
+	region, ok := strings.CutPrefix(host, "sns.")
+	if !ok {
+		return false
+	}
+	region, ok = strings.CutSuffix(region, ".amazonaws.com")
+	if !ok {
+		region, ok = strings.CutSuffix(region, ".amazonaws.com.cn")
+	}
+	return ok && region != "" && !strings.Contains(region, ".")
+}
+
+func text(status int, body string) *Response {
+	return &Response{Status: status, ContentType: "text/plain; charset=utf-8", Body: []byte(body)}
+}
@@ -0,0 +1,50 @@
+package handshake
+
+import (
+	"github.com/AdamShannag/hookah/internal/types"
+	"net/http"
+)
+
+// Response is the reply to a verification request.
+type Response struct {
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// Func answers a provider's URL verification request and reports whether r was one.
+type Func func(cfg types.Handshake, r *http.Request, payload []byte) (*Response, bool)
+
+type Handshake interface {
+	Register(name string, fn Func) Handshake
+	Answer(cfg types.Handshake, r *http.Request, payload []byte) (*Response, bool)
+}
+
+type handshake struct {
+	handlers map[string]Func
+}
+
+func New() Handshake {
+	return &handshake{make(map[string]Func)}
+}
+
+func NewDefault() Handshake {
+	return New().
+		Register("slack", Slack).
+		Register("meta", Meta).
+		Register("graph", Graph).
+		Register("sns", SNS)
+}
+
+func (h *handshake) Register(name string, fn Func) Handshake {
+	h.handlers[name] = fn
+	return h
+}
+
+func (h *handshake) Answer(cfg types.Handshake, r *http.Request, payload []byte) (*Response, bool) {
+	fn, ok := h.handlers[cfg.Type]
+	if !ok {
+		return nil, false
+	}
+	return fn(cfg, r, payload)
+}
@@ -0,0 +1,98 @@
+package handshake
+
+import (
+	"bytes"
+	"errors"
+	"github.com/AdamShannag/hookah/internal/types"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAnswer(t *testing.T) {
+	var confirmed string
+	defer func() { confirmSubscription = confirm }()
+	confirmSubscription = func(subscribeURL string) error {
+		confirmed = subscribeURL
+		if subscribeURL == "https://fail" {
+			return errors.New("boom")
+		}
+		return nil
+	}
+
+	h := NewDefault()
+
+	tests := []struct {
+		name       string
+		cfg        types.Handshake
+		method     string
+		target     string
+		payload    string
+		wantOK     bool
+		wantStatus int
+		wantBody   string
+	}{
+		{"SlackChallenge", types.Handshake{Type: "slack"}, "POST", "/", `{"type":"url_verification","challenge":"abc"}`, true, 200, "abc"},
+		{"SlackEvent", types.Handshake{Type: "slack"}, "POST", "/", `{"type":"event_callback"}`, false, 0, ""},
+		{"MetaChallenge", types.Handshake{Type: "meta", VerifyToken: "tok"}, "GET", "/?hub.mode=subscribe&hub.challenge=1158201444&hub.verify_token=tok", "", true, 200, "1158201444"},
+		{"MetaWrongToken", types.Handshake{Type: "meta", VerifyToken: "tok"}, "GET", "/?hub.mode=subscribe&hub.challenge=1&hub.verify_token=nope", "", true, 403, "Forbidden"},
+		{"MetaNoTokenConfigured", types.Handshake{Type: "meta"}, "GET", "/?hub.mode=subscribe&hub.challenge=1&hub.verify_token=", "", true, 403, "Forbidden"},
+		{"MetaNotSubscribe", types.Handshake{Type: "meta", VerifyToken: "tok"}, "GET", "/", "", false, 0, ""},
+		{"GraphValidation", types.Handshake{Type: "graph"}, "POST", "/?validationToken=Validation%3A+Testing", "", true, 200, "Validation: Testing"},
+		{"GraphNotification", types.Handshake{Type: "graph"}, "POST", "/", `{"value":[]}`, false, 0, ""},
+		{"SNSConfirmation", types.Handshake{Type: "sns"}, "POST", "/", `{"Type":"SubscriptionConfirmation","SubscribeURL":"https://ok"}`, true, 200, ""},
+		{"SNSConfirmationFails", types.Handshake{Type: "sns"}, "POST", "/", `{"Type":"SubscriptionConfirmation","SubscribeURL":"https://fail"}`, true, 502, "Bad Gateway"},
+		{"SNSNotification", types.Handshake{Type: "sns"}, "POST", "/", `{"Type":"Notification"}`, false, 0, ""},
+		{"UnknownType", types.Handshake{Type: "unknown"}, "POST", "/", `{}`, false, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, bytes.NewBufferString(tt.payload))
+
+			resp, ok := h.Answer(tt.cfg, req, []byte(tt.payload))
+			if ok != tt.wantOK {
+				t.Fatalf("expected handled %v, got %v", tt.wantOK, ok)
+			}
+			if !ok {
+				return
+			}
+			if resp.Status != tt.wantStatus || string(resp.Body) != tt.wantBody {
+				t.Errorf("expected %d %q, got %d %q", tt.wantStatus, tt.wantBody, resp.Status, resp.Body)
+			}
+		})
+	}
+
+	if confirmed != "https://fail" {
+		t.Errorf("expected SubscribeURL to be visited, got %q", confirmed)
+	}
+}
+
+func TestIsSNSHost(t *testing.T) {
+	tests := map[string]bool{
+		"sns.us-east-1.amazonaws.com":         true,
+		"sns.cn-north-1.amazonaws.com.cn":     true,
+		"sns.amazonaws.com":                   false,
+		"sns.evil.com":                        false,
+		"sns.us-east-1.amazonaws.com.evil.io": false,
+		"evil.sns.us-east-1.amazonaws.com":    false,
+		"sns.a.b.amazonaws.com":               false,
+	}
+
+	for host, expected := range tests {
+		if got := isSNSHost(host); got != expected {
+			t.Errorf("isSNSHost(%q) = %v, want %v", host, got, expected)
+		}
+	}
+}
+
+func TestConfirmSubscription_RejectsForeignHosts(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("foreign SubscribeURL should not be visited")
+	}))
+	defer server.Close()
+
+	if err := confirm(server.URL); err == nil {
+		t.Fatal("expected foreign host to be rejected")
+	}
+}
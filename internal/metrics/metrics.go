@@ -0,0 +1,6 @@
+package metrics
+
+import "expvar"
+
+// Rejections counts webhook requests dropped before dispatch, keyed by reason.
+var Rejections = expvar.NewMap("hookah_rejections")
@@ -8,17 +8,18 @@ import (
 )
 
 var funcMap = template.FuncMap{
-	"now":       now,
-	"format":    format,
-	"parseTime": parseTime,
-	"pastTense": pastTense,
-	"lower":     strings.ToLower,
-	"upper":     strings.ToUpper,
-	"title":     strings.ToTitle,
-	"trim":      strings.TrimSpace,
-	"contains":  strings.Contains,
-	"replace":   strings.ReplaceAll,
-	"default":   defaultValue,
+	"now":        now,
+	"format":     format,
+	"parseTime":  parseTime,
+	"pastTense":  pastTense,
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"title":      strings.ToTitle,
+	"trim":       strings.TrimSpace,
+	"contains":   strings.Contains,
+	"replace":    strings.ReplaceAll,
+	"default":    defaultValue,
+	"jsonEscape": jsonEscape,
 }
 
 func now() time.Time { return time.Now() }
@@ -4,24 +4,113 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"github.com/AdamShannag/hookah/internal/decode"
+	"strings"
 	"text/template"
+	"text/template/parse"
 )
 
 func ToMap(tmplStr string, dataSource map[string]any) (map[string]any, error) {
+	rendered, err := ToJSON(tmplStr, dataSource)
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]any
+	if err = decode.JSON(rendered, &result); err != nil {
+		return nil, fmt.Errorf("error unmarshaling rendered template: %w", err)
+	}
+
+	return result, nil
+}
+
+// ToJSON renders a template that produces JSON. The output of every action is
+// JSON-escaped, so "{{.title}}" stays a valid JSON string whatever quotes, backslashes
+// or newlines the title contains, while template funcs and comparisons see the raw data.
+func ToJSON(tmplStr string, dataSource map[string]any) ([]byte, error) {
+	tmpl, err := newTemplate(tmplStr)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range tmpl.Templates() {
+		escapeActions(t.Tree.Root)
+	}
+	return execute(tmpl, dataSource)
+}
+
+func ToBytes(tmplStr string, dataSource map[string]any) ([]byte, error) {
+	tmpl, err := newTemplate(tmplStr)
+	if err != nil {
+		return nil, err
+	}
+	return execute(tmpl, dataSource)
+}
+
+func newTemplate(tmplStr string) (*template.Template, error) {
 	tmpl, err := template.New("map-template").Funcs(funcMap).Parse(tmplStr)
 	if err != nil {
 		return nil, fmt.Errorf("error parsing template: %w", err)
 	}
+	return tmpl, nil
+}
 
+func execute(tmpl *template.Template, dataSource map[string]any) ([]byte, error) {
 	var buf bytes.Buffer
-	if err = tmpl.Execute(&buf, dataSource); err != nil {
+	if err := tmpl.Execute(&buf, dataSource); err != nil {
 		return nil, fmt.Errorf("error executing template: %w", err)
 	}
 
-	var result map[string]any
-	if err = json.Unmarshal(buf.Bytes(), &result); err != nil {
-		return nil, fmt.Errorf("error unmarshaling rendered template: %w", err)
+	return buf.Bytes(), nil
+}
+
+// escapeActions appends jsonEscape to the pipeline of every action that prints, the way
+// html/template adds its escapers, unless the pipeline already ends with it.
+func escapeActions(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			escapeActions(child)
+		}
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) > 0 || endsWithEscape(n.Pipe) {
+			return
+		}
+		escape := parse.NewIdentifier("jsonEscape").SetTree(nil).SetPos(n.Pos)
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{NodeType: parse.NodeCommand, Pos: n.Pos, Args: []parse.Node{escape}})
+	case *parse.IfNode:
+		escapeActions(n.List)
+		escapeActions(n.ElseList)
+	case *parse.RangeNode:
+		escapeActions(n.List)
+		escapeActions(n.ElseList)
+	case *parse.WithNode:
+		escapeActions(n.List)
+		escapeActions(n.ElseList)
 	}
+}
 
-	return result, nil
+func endsWithEscape(pipe *parse.PipeNode) bool {
+	last := pipe.Cmds[len(pipe.Cmds)-1]
+	ident, ok := last.Args[0].(*parse.IdentifierNode)
+	return ok && ident.Ident == "jsonEscape"
+}
+
+// jsonEscape prints value as the template would and JSON-escapes the text, without the
+// surrounding quotes. A missing key reaches it as nil and prints "<no value>" as before.
+func jsonEscape(value any) string {
+	if value == nil {
+		return "<no value>"
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(fmt.Sprint(value))
+
+	quoted := strings.TrimSuffix(buf.String(), "\n")
+	return quoted[1 : len(quoted)-1]
 }
@@ -1,6 +1,8 @@
 package render
 
 import (
+	"encoding/json"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -51,3 +53,79 @@ func TestToMap_InvalidJSON(t *testing.T) {
 		t.Fatalf("expected JSON unmarshal error, got: %v", err)
 	}
 }
+
+func TestToBytes_PlainText(t *testing.T) {
+	result, err := ToBytes(`Deploying {{.text | upper}}`, map[string]any{"text": "api"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if string(result) != "Deploying API" {
+		t.Errorf("unexpected result: %s", result)
+	}
+}
+
+func TestToMap_EscapesStrings(t *testing.T) {
+	tmpl := `{"title": "{{.title}}", "labels": "{{index .labels 0}}", "count": {{.count}}}`
+	title := "Fix \"quoted\" path C:\\temp\nand a\ttab <b>&</b>"
+	data := map[string]any{
+		"title":  title,
+		"labels": []any{`"urgent"`},
+		"count":  3,
+	}
+
+	result, err := ToMap(tmpl, data)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if result["title"] != title {
+		t.Errorf("unexpected title: %q", result["title"])
+	}
+	if result["labels"] != `"urgent"` {
+		t.Errorf("unexpected labels: %q", result["labels"])
+	}
+	if result["count"] != json.Number("3") {
+		t.Errorf("unexpected count: %v", result["count"])
+	}
+	if data["title"] != title {
+		t.Error("expected data source to be left untouched")
+	}
+}
+
+func TestToMap_FuncsSeeRawStrings(t *testing.T) {
+	tmpl := `{"upper": "{{upper .s}}", "trim": "{{trim .line}}", "eq": "{{if eq .quote "a\"b"}}match{{end}}", "explicit": "{{jsonEscape .quote}}"}`
+	data := map[string]any{"s": "esc\x1b", "line": "done\n", "quote": `a"b`}
+
+	result, err := ToMap(tmpl, data)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	want := map[string]any{"upper": "ESC\x1b", "trim": "done", "eq": "match", "explicit": `a"b`}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("expected %#v, got %#v", want, result)
+	}
+}
+
+func TestToMap_PreservesLargeIntegers(t *testing.T) {
+	tmpl := `{"id": {{.id}}, "text": "snowflake {{.id}}"}`
+	data := map[string]any{"id": json.Number("1234567890123456789")}
+
+	result, err := ToMap(tmpl, data)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if result["id"] != json.Number("1234567890123456789") {
+		t.Errorf("unexpected id: %v", result["id"])
+	}
+	if result["text"] != "snowflake 1234567890123456789" {
+		t.Errorf("unexpected text: %v", result["text"])
+	}
+
+	encoded, _ := json.Marshal(result)
+	if !strings.Contains(string(encoded), `"id":1234567890123456789`) {
+		t.Errorf("expected exact integer when re-encoded, got: %s", encoded)
+	}
+}
@@ -0,0 +1,187 @@
+package replay
+
+import (
+	"errors"
+	"fmt"
+	"github.com/AdamShannag/hookah/internal/metrics"
+	"github.com/AdamShannag/hookah/internal/resolver"
+	"github.com/AdamShannag/hookah/internal/types"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const defaultMaxAge = 5 * time.Minute
+
+var (
+	ErrMissingNonce     = errors.New("nonce not found")
+	ErrMissingTimestamp = errors.New("timestamp not found")
+	ErrInvalidTimestamp = errors.New("timestamp is not unix seconds or RFC3339")
+	ErrExpired          = errors.New("timestamp outside max age")
+	ErrDuplicate        = errors.New("nonce already seen")
+)
+
+// Guard rejects requests whose nonce was already accepted or whose timestamp is too old.
+type Guard struct {
+	store    Store
+	resolver resolver.Resolver
+}
+
+func NewGuard(store Store) *Guard {
+	return &Guard{store: store, resolver: resolver.NewPathResolver()}
+}
+
+// Filter returns the templates whose replay policy accepts the request. Templates
+// without a policy always pass, and templates of the same receiver sharing a nonce
+// do not reject each other within one request.
+func (g *Guard) Filter(receiver string, templates []types.Template, headers http.Header, body map[string]any) []types.Template {
+	return g.Session().Filter(receiver, templates, headers, body)
+}
+
+// Session filters the items of a single request. Nonces claimed by earlier items are
+// not rejected again, so batch items sharing a delivery header all pass.
+type Session struct {
+	guard   *Guard
+	claimed map[string]bool
+}
+
+func (g *Guard) Session() *Session {
+	return &Session{guard: g, claimed: make(map[string]bool)}
+}
+
+// Filter is Guard.Filter for one item of the session's request.
+func (s *Session) Filter(receiver string, templates []types.Template, headers http.Header, body map[string]any) (accepted []types.Template) {
+	for _, tmpl := range templates {
+		if tmpl.Replay == nil {
+			accepted = append(accepted, tmpl)
+			continue
+		}
+
+		key, err := s.guard.check(receiver, *tmpl.Replay, headers, body, s.claimed)
+		if err != nil {
+			log.Printf("[Replay] rejected for receiver: %s: %v", receiver, err)
+			metrics.Rejections.Add(reason(err), 1)
+			continue
+		}
+
+		s.claimed[key] = true
+		accepted = append(accepted, tmpl)
+	}
+	return
+}
+
+func (g *Guard) check(receiver string, cfg types.Replay, headers http.Header, body map[string]any, claimed map[string]bool) (string, error) {
+	maxAge, err := parseMaxAge(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	// The nonce must outlive every moment its timestamp is accepted, which for a
+	// timestamp up to maxAge in the future is until timestamp+maxAge.
+	ttl := maxAge
+
+	if cfg.TimestampKey != "" {
+		raw, ok := g.lookup(cfg.TimestampIn, cfg.TimestampKey, headers, body)
+		if !ok {
+			return "", fmt.Errorf("%w: %s", ErrMissingTimestamp, cfg.TimestampKey)
+		}
+		timestamp, err := parseTimestamp(raw)
+		if err != nil {
+			return "", err
+		}
+		age := time.Since(timestamp)
+		if age > maxAge || -age > maxAge {
+			return "", fmt.Errorf("%w: %s", ErrExpired, timestamp.Format(time.RFC3339))
+		}
+		ttl = time.Until(timestamp.Add(maxAge))
+	}
+
+	nonce, ok := g.lookup(cfg.NonceIn, cfg.NonceKey, headers, body)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrMissingNonce, cfg.NonceKey)
+	}
+
+	key := receiver + "\x00" + nonce
+	if claimed[key] {
+		return key, nil
+	}
+	if !g.store.Add(key, ttl) {
+		return "", fmt.Errorf("%w: %s", ErrDuplicate, nonce)
+	}
+	return key, nil
+}
+
+// Validate reports a replay policy that would reject every request, or that protects
+// less than it appears to, so it can be caught when the config loads. Without a timestamp
+// a nonce is only remembered for max_age and a replay after that is accepted again, so a
+// nonce-only policy must opt in to that window by setting max_age explicitly.
+func Validate(cfg types.Replay) error {
+	if _, err := parseMaxAge(cfg); err != nil {
+		return err
+	}
+	if cfg.NonceKey == "" || (cfg.NonceIn != "header" && cfg.NonceIn != "body") {
+		return fmt.Errorf("nonce needs nonce_in 'header' or 'body' and a nonce_key")
+	}
+	if cfg.TimestampKey == "" && cfg.MaxAge == "" {
+		return fmt.Errorf("nonce without timestamp_key needs an explicit max_age: nonces are only remembered for max_age, after which a replay is accepted again")
+	}
+	if cfg.TimestampKey != "" && cfg.TimestampIn != "header" && cfg.TimestampIn != "body" {
+		return fmt.Errorf("invalid timestamp_in '%s': expected 'header' or 'body'", cfg.TimestampIn)
+	}
+	return nil
+}
+
+func parseMaxAge(cfg types.Replay) (time.Duration, error) {
+	if cfg.MaxAge == "" {
+		return defaultMaxAge, nil
+	}
+	maxAge, err := time.ParseDuration(cfg.MaxAge)
+	if err != nil {
+		return 0, fmt.Errorf("invalid max_age '%s': %w", cfg.MaxAge, err)
+	}
+	if maxAge <= 0 {
+		return 0, fmt.Errorf("invalid max_age '%s': must be positive", cfg.MaxAge)
+	}
+	return maxAge, nil
+}
+
+func (g *Guard) lookup(in, key string, headers http.Header, body map[string]any) (string, bool) {
+	switch in {
+	case "header":
+		value := headers.Get(key)
+		return value, value != ""
+	case "body":
+		value, err := g.resolver.Resolve(key, body)
+		if err != nil || value == nil {
+			return "", false
+		}
+		s := fmt.Sprint(value)
+		return s, s != ""
+	default:
+		return "", false
+	}
+}
+
+func parseTimestamp(raw string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidTimestamp, raw)
+}
+
+func reason(err error) string {
+	switch {
+	case errors.Is(err, ErrDuplicate):
+		return "replay_duplicate"
+	case errors.Is(err, ErrExpired):
+		return "replay_expired"
+	case errors.Is(err, ErrMissingNonce), errors.Is(err, ErrMissingTimestamp):
+		return "replay_missing_field"
+	default:
+		return "replay_invalid"
+	}
+}
@@ -0,0 +1,221 @@
+package replay
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/AdamShannag/hookah/internal/types"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore_Add(t *testing.T) {
+	now := time.Now()
+	store := &memoryStore{entries: make(map[string]time.Time), now: func() time.Time { return now }}
+
+	if !store.Add("a", time.Minute) {
+		t.Fatal("expected first add to succeed")
+	}
+	if store.Add("a", time.Minute) {
+		t.Fatal("expected second add to be rejected")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if !store.Add("a", time.Minute) {
+		t.Fatal("expected add after expiry to succeed")
+	}
+	if len(store.entries) != 1 {
+		t.Errorf("expected expired entries to be swept, got %d", len(store.entries))
+	}
+}
+
+func TestGuard_Check(t *testing.T) {
+	fresh := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name    string
+		cfg     types.Replay
+		headers http.Header
+		body    map[string]any
+		wantErr error
+	}{
+		{
+			name:    "Header nonce",
+			cfg:     types.Replay{NonceIn: "header", NonceKey: "X-GitHub-Delivery"},
+			headers: http.Header{"X-Github-Delivery": []string{"d-1"}},
+		},
+		{
+			name:    "Body nonce and unix timestamp",
+			cfg:     types.Replay{NonceIn: "body", NonceKey: "event.id", TimestampIn: "header", TimestampKey: "X-Timestamp"},
+			headers: http.Header{"X-Timestamp": []string{fresh}},
+			body:    map[string]any{"event": map[string]any{"id": json.Number("1234567")}},
+		},
+		{
+			name: "RFC3339 body timestamp",
+			cfg:  types.Replay{NonceIn: "body", NonceKey: "id", TimestampIn: "body", TimestampKey: "created_at"},
+			body: map[string]any{"id": "e-1", "created_at": time.Now().UTC().Format(time.RFC3339)},
+		},
+		{
+			name:    "Expired timestamp",
+			cfg:     types.Replay{NonceIn: "header", NonceKey: "X-Id", TimestampIn: "header", TimestampKey: "X-Timestamp"},
+			headers: http.Header{"X-Id": []string{"e-2"}, "X-Timestamp": []string{stale}},
+			wantErr: ErrExpired,
+		},
+		{
+			name:    "Custom max age",
+			cfg:     types.Replay{NonceIn: "header", NonceKey: "X-Id", TimestampIn: "header", TimestampKey: "X-Timestamp", MaxAge: "2h"},
+			headers: http.Header{"X-Id": []string{"e-3"}, "X-Timestamp": []string{stale}},
+		},
+		{
+			name:    "Invalid timestamp",
+			cfg:     types.Replay{NonceIn: "header", NonceKey: "X-Id", TimestampIn: "header", TimestampKey: "X-Timestamp"},
+			headers: http.Header{"X-Id": []string{"e-4"}, "X-Timestamp": []string{"yesterday"}},
+			wantErr: ErrInvalidTimestamp,
+		},
+		{
+			name:    "Missing timestamp",
+			cfg:     types.Replay{NonceIn: "header", NonceKey: "X-Id", TimestampIn: "header", TimestampKey: "X-Timestamp"},
+			headers: http.Header{"X-Id": []string{"e-5"}},
+			wantErr: ErrMissingTimestamp,
+		},
+		{
+			name:    "Missing nonce",
+			cfg:     types.Replay{NonceIn: "header", NonceKey: "X-Id"},
+			headers: http.Header{},
+			wantErr: ErrMissingNonce,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGuard(NewMemoryStore())
+
+			_, err := g.check("receiver", tt.cfg, tt.headers, tt.body, map[string]bool{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			_, err = g.check("receiver", tt.cfg, tt.headers, tt.body, map[string]bool{})
+			if !errors.Is(err, ErrDuplicate) {
+				t.Errorf("expected replay to be rejected, got %v", err)
+			}
+		})
+	}
+}
+
+func TestGuard_Filter(t *testing.T) {
+	g := NewGuard(NewMemoryStore())
+	policy := &types.Replay{NonceIn: "header", NonceKey: "X-Id"}
+	templates := []types.Template{
+		{Receiver: "github", Replay: policy},
+		{Receiver: "github", Replay: policy},
+		{Receiver: "github"},
+	}
+	headers := http.Header{"X-Id": []string{"delivery-1"}}
+
+	if got := g.Filter("github", templates, headers, nil); len(got) != 3 {
+		t.Fatalf("expected all templates on first delivery, got %d", len(got))
+	}
+
+	if got := g.Filter("github", templates, headers, nil); len(got) != 1 {
+		t.Fatalf("expected only the unprotected template on replay, got %d", len(got))
+	}
+
+	if got := g.Filter("gitlab", templates, headers, nil); len(got) != 3 {
+		t.Fatalf("expected nonces to be scoped per receiver, got %d", len(got))
+	}
+}
+
+func TestSession_Filter(t *testing.T) {
+	g := NewGuard(NewMemoryStore())
+	templates := []types.Template{
+		{Receiver: "sentry", Replay: &types.Replay{NonceIn: "header", NonceKey: "X-Id"}},
+		{Receiver: "sentry", Replay: &types.Replay{NonceIn: "body", NonceKey: "id"}},
+	}
+	headers := http.Header{"X-Id": []string{"delivery-1"}}
+
+	session := g.Session()
+	for _, id := range []string{"a", "b", "c"} {
+		if got := session.Filter("sentry", templates, headers, map[string]any{"id": id}); len(got) != 2 {
+			t.Fatalf("expected item %s to pass both templates, got %d", id, len(got))
+		}
+	}
+
+	if got := g.Session().Filter("sentry", templates, headers, map[string]any{"id": "d"}); len(got) != 1 {
+		t.Fatalf("expected a new request to reject the reused delivery header, got %d", len(got))
+	}
+}
+
+func TestGuard_KeepsNonceWhileTimestampIsAccepted(t *testing.T) {
+	store := &recordingStore{}
+	g := NewGuard(store)
+	cfg := types.Replay{NonceIn: "header", NonceKey: "X-Id", TimestampIn: "header", TimestampKey: "X-Timestamp", MaxAge: "5m"}
+	future := strconv.FormatInt(time.Now().Add(4*time.Minute).Unix(), 10)
+
+	if _, err := g.check("receiver", cfg, http.Header{"X-Id": []string{"e-1"}, "X-Timestamp": []string{future}}, nil, map[string]bool{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if store.ttl < 8*time.Minute {
+		t.Errorf("expected nonce to be kept until timestamp+max_age, got ttl %v", store.ttl)
+	}
+}
+
+func TestGuard_NonceOnlyRememberedForMaxAge(t *testing.T) {
+	clock := time.Now()
+	store := &memoryStore{entries: make(map[string]time.Time), now: func() time.Time { return clock }}
+	g := NewGuard(store)
+	cfg := types.Replay{NonceIn: "header", NonceKey: "X-GitHub-Delivery", MaxAge: "24h"}
+	headers := http.Header{"X-Github-Delivery": []string{"d-1"}}
+
+	if _, err := g.check("github", cfg, headers, nil, map[string]bool{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	clock = clock.Add(23 * time.Hour)
+	if _, err := g.check("github", cfg, headers, nil, map[string]bool{}); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("expected replay within max_age to be rejected, got %v", err)
+	}
+
+	clock = clock.Add(2 * time.Hour)
+	if _, err := g.check("github", cfg, headers, nil, map[string]bool{}); err != nil {
+		t.Fatalf("expected nonce to be forgotten after max_age, got %v", err)
+	}
+}
+
+type recordingStore struct{ ttl time.Duration }
+
+func (s *recordingStore) Add(_ string, ttl time.Duration) bool {
+	s.ttl = ttl
+	return true
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     types.Replay
+		wantErr bool
+	}{
+		{"Valid", types.Replay{NonceIn: "header", NonceKey: "X-Id", MaxAge: "10m"}, false},
+		{"Default max age", types.Replay{NonceIn: "body", NonceKey: "id", TimestampIn: "body", TimestampKey: "ts"}, false},
+		{"Typo in max age", types.Replay{NonceIn: "header", NonceKey: "X-Id", MaxAge: "10 minutes"}, true},
+		{"Negative max age", types.Replay{NonceIn: "header", NonceKey: "X-Id", MaxAge: "-1m"}, true},
+		{"Nonce only without max age", types.Replay{NonceIn: "header", NonceKey: "X-GitHub-Delivery"}, true},
+		{"Nonce only with max age", types.Replay{NonceIn: "header", NonceKey: "X-GitHub-Delivery", MaxAge: "720h"}, false},
+		{"Missing nonce key", types.Replay{NonceIn: "header"}, true},
+		{"Invalid nonce source", types.Replay{NonceIn: "query", NonceKey: "id"}, true},
+		{"Invalid timestamp source", types.Replay{NonceIn: "header", NonceKey: "X-Id", TimestampIn: "query", TimestampKey: "ts"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
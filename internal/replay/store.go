@@ -0,0 +1,48 @@
+package replay
+
+import (
+	"sync"
+	"time"
+)
+
+// Store remembers keys for a limited time.
+type Store interface {
+	// Add records key for ttl and reports whether it was not already present.
+	Add(key string, ttl time.Duration) bool
+}
+
+// memoryStore is an in-process Store that lazily evicts expired keys.
+type memoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]time.Time
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+const sweepInterval = time.Minute
+
+func NewMemoryStore() Store {
+	return &memoryStore{entries: make(map[string]time.Time), now: time.Now}
+}
+
+func (s *memoryStore) Add(key string, ttl time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) > sweepInterval {
+		for k, expiry := range s.entries {
+			if !now.Before(expiry) {
+				delete(s.entries, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	if expiry, ok := s.entries[key]; ok && now.Before(expiry) {
+		return false
+	}
+
+	s.entries[key] = now.Add(ttl)
+	return true
+}
@@ -57,3 +57,10 @@ func BenchmarkResolveProjectedPath(b *testing.B) {
 		_, _ = resolver.Resolve("user.projects[].tasks", benchmarkData)
 	}
 }
+
+func BenchmarkResolveCompiledNestedPath(b *testing.B) {
+	access := NewPathResolver().(Compiler).Compile("user.address.city")
+	for i := 0; i < b.N; i++ {
+		_, _ = access(benchmarkData)
+	}
+}
@@ -1,16 +1,29 @@
 package resolver
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 )
 
+// ErrNotFound is wrapped by Resolve when a key or index on the path is absent.
+var ErrNotFound = errors.New("path not found")
+
 // Resolver defines the interface for any value resolver.
 type Resolver interface {
 	Resolve(path string, data any) (any, error)
 }
 
+// Accessor resolves a path that was parsed ahead of time.
+type Accessor func(data any) (any, error)
+
+// Compiler is implemented by resolvers that can parse a path once and resolve it many
+// times, such as conditions compiled at config load.
+type Compiler interface {
+	Compile(path string) Accessor
+}
+
 // pathResolver resolves dotted paths and supports array access and projection.
 type pathResolver struct{}
 
@@ -20,54 +33,77 @@ func NewPathResolver() Resolver {
 
 // Resolve a value by navigating the provided path (e.g. "users[0].name" or "users[].name").
 func (r *pathResolver) Resolve(path string, data any) (any, error) {
-	parts := strings.Split(path, ".")
-	return resolvePath(data, parts)
+	return resolvePath(data, parsePath(path))
 }
 
-// resolvePath resolves the nested path on a given data structure.
-func resolvePath(current any, parts []string) (any, error) {
+// Compile parses path once; the returned Accessor behaves like Resolve(path, data).
+func (r *pathResolver) Compile(path string) Accessor {
+	segments := parsePath(path)
+	return func(data any) (any, error) {
+		return resolvePath(data, segments)
+	}
+}
+
+// segment is one dotted part of a path, such as "users", "users[0]" or "users[]".
+type segment struct {
+	part      string
+	key       string
+	index     *int
+	isIndexed bool
+}
+
+func parsePath(path string) []segment {
+	parts := strings.Split(path, ".")
+	segments := make([]segment, len(parts))
 	for i, part := range parts {
 		key, index, isIndexed := parseIndexedKey(part)
+		segments[i] = segment{part: part, key: key, index: index, isIndexed: isIndexed}
+	}
+	return segments
+}
 
+// resolvePath resolves the nested path on a given data structure.
+func resolvePath(current any, segments []segment) (any, error) {
+	for i, seg := range segments {
 		objMap, ok := current.(map[string]any)
 		if !ok {
-			return nil, fmt.Errorf("expected map at '%s'", part)
+			return nil, fmt.Errorf("expected map at '%s'", seg.part)
 		}
 
-		value, exists := objMap[key]
+		value, exists := objMap[seg.key]
 		if !exists {
-			return nil, fmt.Errorf("key '%s' not found", key)
+			return nil, fmt.Errorf("key '%s': %w", seg.key, ErrNotFound)
 		}
 
-		if !isIndexed {
+		if !seg.isIndexed {
 			current = value
 			continue
 		}
 
 		array, ok := value.([]any)
 		if !ok {
-			return nil, fmt.Errorf("expected array at '%s'", key)
+			return nil, fmt.Errorf("expected array at '%s'", seg.key)
 		}
 
-		if index == nil {
-			return projectArray(array, parts[i+1:])
+		if seg.index == nil {
+			return projectArray(array, segments[i+1:])
 		}
 
-		if *index < 0 || *index >= len(array) {
-			return nil, fmt.Errorf("index out of bounds at '%s[%d]'", key, *index)
+		if *seg.index < 0 || *seg.index >= len(array) {
+			return nil, fmt.Errorf("index out of bounds at '%s[%d]': %w", seg.key, *seg.index, ErrNotFound)
 		}
 
-		current = array[*index]
+		current = array[*seg.index]
 	}
 
 	return current, nil
 }
 
-// projectArray handles projection through an array of maps using the remaining path parts.
-func projectArray(array []any, remainingParts []string) ([]any, error) {
+// projectArray handles projection through an array of maps using the remaining path segments.
+func projectArray(array []any, remaining []segment) ([]any, error) {
 	results := make([]any, 0, len(array))
 	for _, item := range array {
-		val, err := resolvePath(item, remainingParts)
+		val, err := resolvePath(item, remaining)
 		if err != nil {
 			continue
 		}
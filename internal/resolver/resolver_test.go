@@ -1,6 +1,7 @@
 package resolver
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 )
@@ -81,3 +82,51 @@ func TestPathResolver_Resolve(t *testing.T) {
 		})
 	}
 }
+
+func TestPathResolver_ErrNotFound(t *testing.T) {
+	resolver := NewPathResolver()
+	data := map[string]any{
+		"user": map[string]any{"name": "Alice", "tags": []any{"admin"}},
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		notFound bool
+	}{
+		{"Missing key", "user.assignee", true},
+		{"Missing parent", "issue.assignee.name", true},
+		{"Index out of bounds", "user.tags[3]", true},
+		{"Index on non-array", "user.name[0]", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := resolver.Resolve(tt.path, data)
+			if err == nil {
+				t.Fatal("Resolve() expected an error")
+			}
+			if errors.Is(err, ErrNotFound) != tt.notFound {
+				t.Errorf("errors.Is(%v, ErrNotFound) = %v, want %v", err, !tt.notFound, tt.notFound)
+			}
+		})
+	}
+}
+
+func TestPathResolver_Compile(t *testing.T) {
+	resolver := NewPathResolver()
+	data := map[string]any{
+		"user": map[string]any{
+			"tags":    []any{"admin", "user"},
+			"friends": []any{map[string]any{"name": "Bob"}, map[string]any{"name": "Charlie"}},
+		},
+	}
+
+	for _, path := range []string{"user.tags[1]", "user.friends[].name", "user.missing", "user.tags[9]"} {
+		want, wantErr := resolver.Resolve(path, data)
+		got, err := resolver.(Compiler).Compile(path)(data)
+		if !reflect.DeepEqual(got, want) || (err != nil) != (wantErr != nil) {
+			t.Errorf("Compile(%q) = %#v, %v, want %#v, %v", path, got, err, want, wantErr)
+		}
+	}
+}
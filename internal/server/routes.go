@@ -1,14 +1,30 @@
 package server
 
 import (
-	"encoding/json"
+	"errors"
+	"github.com/AdamShannag/hookah/internal/config"
+	"github.com/AdamShannag/hookah/internal/flow"
+	"github.com/AdamShannag/hookah/internal/replay"
+	"github.com/AdamShannag/hookah/internal/types"
 	"io"
+	"log"
 	"net/http"
+	"slices"
 )
 
+// claimsKey is the body key under which verified token claims are exposed to
+// conditions ({Body._claims.sub}) and templates ({{._claims.sub}}). Any value the
+// client sent under this key is dropped, so it only ever holds verified claims.
+const claimsKey = "_claims"
+
+// forwardingHeaders are appended by trusted proxies and read by the IP allowlist, so a
+// query parameter of the same name must not replace them.
+var forwardingHeaders = []string{"X-Forwarded-For", "Forwarded"}
+
 func (s *Server) RegisterRoutes() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("POST /webhooks/{receiver}", s.WebhookHandler)
+	mux.HandleFunc("GET /webhooks/{receiver}", s.HandshakeHandler)
 	return mux
 }
 
@@ -16,32 +32,105 @@ func (s *Server) WebhookHandler(w http.ResponseWriter, r *http.Request) {
 	receiver := r.PathValue("receiver")
 
 	for key, _ := range r.URL.Query() {
+		if slices.Contains(forwardingHeaders, http.CanonicalHeaderKey(key)) {
+			continue
+		}
 		r.Header.Set(key, r.URL.Query().Get(key))
 	}
 
 	payload, err := io.ReadAll(r.Body)
-	if err != nil || len(payload) == 0 {
-		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
-	payload = escapeEscapedQuotes(payload)
 
-	templates := s.config.GetConfigTemplates(receiver, r, payload)
+	r = flow.WithClaims(r)
+	templates, err := s.config.GetConfigTemplates(receiver, r, payload)
+	switch {
+	case errors.Is(err, config.ErrUnknownReceiver):
+		reply(w, s.unknownReceiverStatus)
+		return
+	case err != nil:
+		reply(w, s.authFailureStatus)
+		return
+	}
 
-	if len(templates) == 0 {
-		w.WriteHeader(http.StatusOK)
+	if s.answerHandshake(w, r, templates, payload) {
+		return
+	}
+
+	if len(payload) == 0 {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	var request map[string]interface{}
-	if err = json.Unmarshal(payload, &request); err != nil {
-		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+	items, err := decodePayload(r.Header.Get("Content-Type"), payload, templates)
+	if err != nil {
+		log.Printf("[Payload] %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	for _, tmpl := range templates {
-		go s.handleTemplate(tmpl, r.Header, request)
+	claims := flow.Claims(r)
+	var session *replay.Session
+	if s.replay != nil {
+		session = s.replay.Session()
+	}
+
+	var response *types.Response
+	var responseData map[string]any
+	for i, request := range items {
+		delete(request, claimsKey)
+		if claims != nil {
+			request[claimsKey] = claims
+		}
+
+		accepted := templates
+		if session != nil {
+			accepted = session.Filter(receiver, templates, r.Header, request)
+		}
+
+		if response == nil {
+			response, responseData = s.selectResponse(accepted, r.Header, request), request
+		}
+
+		if len(items) > 1 {
+			log.Printf("[Batch] Item %d/%d for receiver: %s dispatched to %d template(s)", i+1, len(items), receiver, len(accepted))
+		}
+
+		for _, tmpl := range accepted {
+			go s.handleTemplate(tmpl, r.Header, request)
+		}
+	}
+
+	if response != nil {
+		s.writeResponse(w, response, responseData)
+		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
+
+// HandshakeHandler answers GET verification requests, such as Meta's hub.challenge. These
+// carry no signature, so each handshake checks its own verify token instead of the
+// template's auth flow.
+func (s *Server) HandshakeHandler(w http.ResponseWriter, r *http.Request) {
+	templates := s.config.GetReceiverTemplates(r.PathValue("receiver"))
+	if len(templates) == 0 {
+		reply(w, s.unknownReceiverStatus)
+		return
+	}
+
+	if !s.answerHandshake(w, r, templates, nil) {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// reply writes an empty response with status, defaulting to 200 when unset.
+func reply(w http.ResponseWriter, status int) {
+	if status == 0 || status == http.StatusOK {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	http.Error(w, http.StatusText(status), status)
+}
@@ -2,16 +2,23 @@ package server
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"github.com/AdamShannag/hookah/internal/auth"
 	"github.com/AdamShannag/hookah/internal/condition"
 	"github.com/AdamShannag/hookah/internal/config"
+	"github.com/AdamShannag/hookah/internal/handshake"
+	"github.com/AdamShannag/hookah/internal/replay"
 	"github.com/AdamShannag/hookah/internal/resolver"
 	"github.com/AdamShannag/hookah/internal/types"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"slices"
 	"sync"
 	"testing"
 	"time"
@@ -222,6 +229,640 @@ func TestWebhookHandler_UsesQueryParamsAsHeaders(t *testing.T) {
 	}
 }
 
+func TestWebhookHandler_QueryParamsCannotForgeForwardingHeaders(t *testing.T) {
+	templates := []types.Template{{
+		Receiver: "ci",
+		Auth: types.Auth{
+			Flow:           "ip allowlist",
+			AllowedCIDRs:   []string{"192.30.252.0/22"},
+			TrustedProxies: []string{"10.0.0.0/8"},
+		},
+	}}
+
+	testServer := &Server{
+		evaluator:         condition.NewDefaultEvaluator(resolver.NewPathResolver()),
+		config:            config.New(templates, nil, auth.NewDefault()),
+		authFailureStatus: http.StatusForbidden,
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		forwarded string
+		expected  int
+	}{
+		{"ProxyChainAllowed", "", "192.30.252.1", http.StatusOK},
+		{"QueryXFFIgnored", "?X-Forwarded-For=192.30.252.1", "203.0.113.9", http.StatusForbidden},
+		{"QueryLowercaseXFFIgnored", "?x-forwarded-for=192.30.252.1", "203.0.113.9", http.StatusForbidden},
+		{"QueryForwardedIgnored", "?Forwarded=for%3D192.30.252.1", "203.0.113.9", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/ci"+tt.query, bytes.NewBufferString(`{}`))
+			req.RemoteAddr = "10.0.0.5:443"
+			req.Header.Set("X-Forwarded-For", tt.forwarded)
+			rr := httptest.NewRecorder()
+			testServer.RegisterRoutes().ServeHTTP(rr, req)
+
+			if rr.Code != tt.expected {
+				t.Fatalf("expected status %d, got %d", tt.expected, rr.Code)
+			}
+		})
+	}
+}
+
+func TestWebhookHandler_ExposesJWTClaims(t *testing.T) {
+	var (
+		receivedPayload map[string]any
+		mu              sync.Mutex
+		wg              sync.WaitGroup
+	)
+
+	wg.Add(1)
+	mockDiscord := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		defer mu.Unlock()
+		_ = json.Unmarshal(body, &receivedPayload)
+		wg.Done()
+	}))
+	defer mockDiscord.Close()
+
+	testServer := &Server{
+		evaluator: condition.NewDefaultEvaluator(resolver.NewPathResolver()),
+		config: config.New([]types.Template{
+			{
+				Receiver:     "internal",
+				Auth:         types.Auth{Flow: "jwt", Secret: "shared"},
+				EventTypeKey: "event_name",
+				EventTypeIn:  "body",
+				Events: types.Events{
+					{
+						Event:      "deploy",
+						Conditions: []string{"{Body._claims.sub} {eq} {ci-bot}"},
+						Hooks: []types.Hook{
+							{
+								Name:        "MockDiscord",
+								EndpointKey: "Webhook-URL",
+								Body:        "discord.tmpl",
+							},
+						},
+					},
+				},
+			},
+		}, map[string]string{
+			"discord.tmpl": `{"content": "deployed by {{._claims.sub}}"}`,
+		}, auth.NewDefault()),
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"ci-bot"}`))
+	mac := hmac.New(sha256.New, []byte("shared"))
+	mac.Write([]byte(signed))
+	token := signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/internal", bytes.NewBufferString(`{"event_name":"deploy"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Webhook-URL", mockDiscord.URL)
+
+	rr := httptest.NewRecorder()
+	testServer.RegisterRoutes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rr.Code)
+	}
+
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if receivedPayload["content"] != "deployed by ci-bot" {
+		t.Fatalf("expected claims in payload, got: %v", receivedPayload)
+	}
+}
+
+func TestWebhookHandler_DropsForgedClaims(t *testing.T) {
+	var (
+		receivedPayload map[string]any
+		mu              sync.Mutex
+		wg              sync.WaitGroup
+	)
+
+	wg.Add(1)
+	mockDiscord := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		defer mu.Unlock()
+		_ = json.Unmarshal(body, &receivedPayload)
+		wg.Done()
+	}))
+	defer mockDiscord.Close()
+
+	hook := []types.Hook{{Name: "MockDiscord", EndpointKey: "Webhook-URL", Body: "discord.tmpl"}}
+	testServer := &Server{
+		evaluator: condition.NewDefaultEvaluator(resolver.NewPathResolver()),
+		config: config.New([]types.Template{
+			{
+				Receiver:     "internal",
+				Auth:         types.Auth{Any: []types.Auth{{Flow: "jwt", Secret: "shared"}, {Flow: "none"}}},
+				EventTypeKey: "event_name",
+				EventTypeIn:  "body",
+				Events: types.Events{
+					{Event: "deploy", Conditions: []string{"{Body._claims.sub} {eq} {admin}"}, Hooks: hook},
+					{Event: "deploy", Conditions: []string{"{Body._claims} {notExists}"}, Hooks: hook},
+				},
+			},
+		}, map[string]string{
+			"discord.tmpl": `{"content": "claims: {{._claims}}"}`,
+		}, auth.NewDefault()),
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/internal",
+		bytes.NewBufferString(`{"event_name":"deploy","_claims":{"sub":"admin"}}`))
+	req.Header.Set("Webhook-URL", mockDiscord.URL)
+
+	rr := httptest.NewRecorder()
+	testServer.RegisterRoutes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rr.Code)
+	}
+
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if receivedPayload["content"] != "claims: <no value>" {
+		t.Fatalf("expected forged claims to be dropped, got: %v", receivedPayload)
+	}
+}
+
+func TestWebhookHandler_RejectionStatuses(t *testing.T) {
+	templates := []types.Template{
+		{Receiver: "github", Auth: types.Auth{Flow: "github", HeaderSecretKey: "X-Hub-Signature-256", Secret: "s"}},
+	}
+
+	tests := []struct {
+		name                  string
+		receiver              string
+		authFailureStatus     int
+		unknownReceiverStatus int
+		expected              int
+	}{
+		{"AuthFailureDefault", "github", 0, 0, http.StatusOK},
+		{"AuthFailureUnauthorized", "github", http.StatusUnauthorized, 0, http.StatusUnauthorized},
+		{"AuthFailureForbidden", "github", http.StatusForbidden, 0, http.StatusForbidden},
+		{"UnknownReceiverDefault", "gitlab", http.StatusUnauthorized, 0, http.StatusOK},
+		{"UnknownReceiverNotFound", "gitlab", http.StatusUnauthorized, http.StatusNotFound, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testServer := &Server{
+				evaluator:             condition.NewDefaultEvaluator(resolver.NewPathResolver()),
+				config:                config.New(templates, nil, auth.NewDefault()),
+				authFailureStatus:     tt.authFailureStatus,
+				unknownReceiverStatus: tt.unknownReceiverStatus,
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/"+tt.receiver, bytes.NewBufferString(`{}`))
+			rr := httptest.NewRecorder()
+			testServer.RegisterRoutes().ServeHTTP(rr, req)
+
+			if rr.Code != tt.expected {
+				t.Fatalf("expected status %d, got %d", tt.expected, rr.Code)
+			}
+		})
+	}
+}
+
+func TestWebhookHandler_AnswersHandshakes(t *testing.T) {
+	testServer := &Server{
+		evaluator: condition.NewDefaultEvaluator(resolver.NewPathResolver()),
+		handshake: handshake.NewDefault(),
+		config: config.New([]types.Template{
+			{
+				Receiver:  "slack",
+				Auth:      types.Auth{Flow: "none"},
+				Handshake: &types.Handshake{Type: "slack"},
+			},
+			{
+				Receiver:  "meta",
+				Auth:      types.Auth{Flow: "github", HeaderSecretKey: "X-Hub-Signature-256", Secret: "app-secret"},
+				Handshake: &types.Handshake{Type: "meta", VerifyToken: "verify-me"},
+			},
+			{
+				Receiver:  "graph",
+				Auth:      types.Auth{Flow: "none"},
+				Handshake: &types.Handshake{Type: "graph"},
+			},
+		}, nil, auth.NewDefault()),
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"SlackChallenge", http.MethodPost, "/webhooks/slack", `{"type":"url_verification","challenge":"c-1"}`, http.StatusOK, "c-1"},
+		{"MetaChallenge", http.MethodGet, "/webhooks/meta?hub.mode=subscribe&hub.challenge=42&hub.verify_token=verify-me", "", http.StatusOK, "42"},
+		{"MetaWrongToken", http.MethodGet, "/webhooks/meta?hub.mode=subscribe&hub.challenge=42&hub.verify_token=guess", "", http.StatusForbidden, "Forbidden"},
+		{"GraphValidation", http.MethodPost, "/webhooks/graph?validationToken=abc", "", http.StatusOK, "abc"},
+		{"GetWithoutHandshake", http.MethodGet, "/webhooks/slack", "", http.StatusMethodNotAllowed, "Method Not Allowed\n"},
+		{"EmptyBodyWithoutHandshake", http.MethodPost, "/webhooks/slack", "", http.StatusBadRequest, "Invalid request body\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+			testServer.RegisterRoutes().ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus || rr.Body.String() != tt.wantBody {
+				t.Fatalf("expected %d %q, got %d %q", tt.wantStatus, tt.wantBody, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestWebhookHandler_RendersResponseTemplate(t *testing.T) {
+	testServer := &Server{
+		evaluator: condition.NewDefaultEvaluator(resolver.NewPathResolver()),
+		config: config.New([]types.Template{
+			{
+				Receiver:     "slack",
+				Auth:         types.Auth{Flow: "none"},
+				EventTypeKey: "command",
+				EventTypeIn:  "body",
+				Response:     &types.Response{Body: "ack.tmpl", ContentType: "text/plain"},
+				Events: types.Events{
+					{
+						Event:      "/deploy",
+						Conditions: []string{"{Body.text} {eq} {prod}"},
+						Response:   &types.Response{Body: "deploy.tmpl", Status: http.StatusAccepted},
+					},
+				},
+			},
+		}, map[string]string{
+			"ack.tmpl":    `Got {{.command}}`,
+			"deploy.tmpl": `{"response_type": "in_channel", "text": "Deploying to {{.text}}"}`,
+		}, auth.NewDefault()),
+	}
+
+	tests := []struct {
+		name            string
+		body            string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{"EventResponse", `{"command":"/deploy","text":"prod"}`, http.StatusAccepted, "application/json", `{"response_type": "in_channel", "text": "Deploying to prod"}`},
+		{"ConditionFailsFallsBack", `{"command":"/deploy","text":"staging"}`, http.StatusOK, "text/plain", "Got /deploy"},
+		{"UnknownEventFallsBack", `{"command":"/status"}`, http.StatusOK, "text/plain", "Got /status"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/slack", bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+			testServer.RegisterRoutes().ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus || rr.Header().Get("Content-Type") != tt.wantContentType || rr.Body.String() != tt.wantBody {
+				t.Fatalf("expected %d %s %q, got %d %s %q", tt.wantStatus, tt.wantContentType, tt.wantBody,
+					rr.Code, rr.Header().Get("Content-Type"), rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestWebhookHandler_DecodesFormPayloads(t *testing.T) {
+	testServer := &Server{
+		evaluator: condition.NewDefaultEvaluator(resolver.NewPathResolver()),
+		config: config.New([]types.Template{
+			{
+				Receiver:     "slack",
+				Auth:         types.Auth{Flow: "none"},
+				EventTypeKey: "command",
+				EventTypeIn:  "body",
+				Payload:      &types.Payload{JSONFields: []string{"payload"}},
+				Events: types.Events{
+					{
+						Event:      "/deploy",
+						Conditions: []string{"{Body.payload.env} {eq} {prod}"},
+						Response:   &types.Response{Body: "ack.tmpl", ContentType: "text/plain"},
+					},
+				},
+			},
+		}, map[string]string{
+			"ack.tmpl": `{{.command}} {{.payload.env}} by {{.user_name}}`,
+		}, auth.NewDefault()),
+	}
+
+	form := url.Values{
+		"command":   {"/deploy"},
+		"user_name": {"adam"},
+		"payload":   {`{"env":"prod"}`},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/slack", bytes.NewBufferString(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	rr := httptest.NewRecorder()
+	testServer.RegisterRoutes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK || rr.Body.String() != "/deploy prod by adam" {
+		t.Fatalf("expected rendered form response, got %d %q", rr.Code, rr.Body.String())
+	}
+}
+
+func TestWebhookHandler_DecodesXMLPayloads(t *testing.T) {
+	testServer := &Server{
+		evaluator: condition.NewDefaultEvaluator(resolver.NewPathResolver()),
+		config: config.New([]types.Template{
+			{
+				Receiver:     "jenkins",
+				Auth:         types.Auth{Flow: "none"},
+				EventTypeKey: "X-Event",
+				EventTypeIn:  "header",
+				Events: types.Events{
+					{
+						Event: "build",
+						Conditions: []string{
+							"{Body.build.@result} {eq} {SUCCESS}",
+							"{bob} {in} {Body.build.changeSet.item[].@author}",
+						},
+						Response: &types.Response{Body: "ack.tmpl", ContentType: "text/plain"},
+					},
+				},
+			},
+		}, map[string]string{
+			"ack.tmpl": `{{.build.name}} #{{index .build "@id"}}`,
+		}, auth.NewDefault()),
+	}
+
+	payload := `<build id="42" result="SUCCESS"><name>nightly</name><changeSet>` +
+		`<item author="alice"/><item author="bob"/></changeSet></build>`
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/jenkins", bytes.NewBufferString(payload))
+	req.Header.Set("Content-Type", "application/xml")
+	req.Header.Set("X-Event", "build")
+
+	rr := httptest.NewRecorder()
+	testServer.RegisterRoutes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK || rr.Body.String() != "nightly #42" {
+		t.Fatalf("expected rendered XML response, got %d %q", rr.Code, rr.Body.String())
+	}
+}
+
+func TestWebhookHandler_SplitsBatches(t *testing.T) {
+	var (
+		received []string
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+	)
+
+	mockDiscord := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		var payload map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, payload["content"].(string))
+		wg.Done()
+	}))
+	defer mockDiscord.Close()
+
+	newServer := func(batch bool) *Server {
+		return &Server{
+			evaluator: condition.NewDefaultEvaluator(resolver.NewPathResolver()),
+			config: config.New([]types.Template{
+				{
+					Receiver:     "sentry",
+					Auth:         types.Auth{Flow: "none"},
+					EventTypeKey: "action",
+					EventTypeIn:  "body",
+					Payload:      &types.Payload{Batch: batch},
+					Events: types.Events{
+						{
+							Event:      "created",
+							Conditions: []string{"{Body.level} {eq} {error}"},
+							Hooks:      []types.Hook{{Name: "MockDiscord", EndpointKey: "Webhook-URL", Body: "discord.tmpl"}},
+						},
+					},
+				},
+			}, map[string]string{
+				"discord.tmpl": `{"content": "{{.id}}"}`,
+			}, auth.NewDefault()),
+		}
+	}
+
+	payload := `[{"action":"created","id":"a","level":"error"},` +
+		`{"action":"created","id":"b","level":"info"},` +
+		`{"action":"created","id":"c","level":"error"}]`
+
+	t.Run("batch disabled", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/sentry", bytes.NewBufferString(payload))
+		rr := httptest.NewRecorder()
+		newServer(false).RegisterRoutes().ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", rr.Code)
+		}
+	})
+
+	t.Run("batch enabled", func(t *testing.T) {
+		wg.Add(2)
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/sentry", bytes.NewBufferString(payload))
+		req.Header.Set("Webhook-URL", mockDiscord.URL)
+		rr := httptest.NewRecorder()
+		newServer(true).RegisterRoutes().ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rr.Code)
+		}
+
+		wg.Wait()
+
+		mu.Lock()
+		defer mu.Unlock()
+		slices.Sort(received)
+		if !slices.Equal(received, []string{"a", "c"}) {
+			t.Fatalf("expected items a and c to be dispatched, got: %v", received)
+		}
+	})
+}
+
+func TestWebhookHandler_RejectsNonObjectPayloads(t *testing.T) {
+	testServer := &Server{
+		evaluator: condition.NewDefaultEvaluator(resolver.NewPathResolver()),
+		config: config.New([]types.Template{
+			{Receiver: "sentry", Auth: types.Auth{Flow: "none"}, Payload: &types.Payload{Batch: true}},
+		}, nil, auth.NewDefault()),
+	}
+
+	for _, payload := range []string{`null`, `[{"id":"a"}, null]`} {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/sentry", bytes.NewBufferString(payload))
+		rr := httptest.NewRecorder()
+		testServer.RegisterRoutes().ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for %s, got %d", payload, rr.Code)
+		}
+	}
+}
+
+func TestWebhookHandler_BatchSharesReplayNonce(t *testing.T) {
+	var (
+		received []string
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+	)
+
+	mockDiscord := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		var payload map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, payload["content"].(string))
+		wg.Done()
+	}))
+	defer mockDiscord.Close()
+
+	testServer := &Server{
+		evaluator: condition.NewDefaultEvaluator(resolver.NewPathResolver()),
+		replay:    replay.NewGuard(replay.NewMemoryStore()),
+		config: config.New([]types.Template{
+			{
+				Receiver:     "sentry",
+				Auth:         types.Auth{Flow: "none"},
+				EventTypeKey: "action",
+				EventTypeIn:  "body",
+				Payload:      &types.Payload{Batch: true},
+				Replay:       &types.Replay{NonceIn: "header", NonceKey: "X-Delivery", MaxAge: "1h"},
+				Events: types.Events{
+					{
+						Event: "created",
+						Hooks: []types.Hook{{Name: "MockDiscord", EndpointKey: "Webhook-URL", Body: "discord.tmpl"}},
+					},
+				},
+			},
+		}, map[string]string{
+			"discord.tmpl": `{"content": "{{.id}}"}`,
+		}, auth.NewDefault()),
+	}
+
+	payload := `[{"action":"created","id":"a"},{"action":"created","id":"b"},{"action":"created","id":"c"}]`
+	send := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/sentry", bytes.NewBufferString(payload))
+		req.Header.Set("X-Delivery", "delivery-1")
+		req.Header.Set("Webhook-URL", mockDiscord.URL)
+		rr := httptest.NewRecorder()
+		testServer.RegisterRoutes().ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	wg.Add(3)
+	if code := send(); code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", code)
+	}
+	wg.Wait()
+
+	if code := send(); code != http.StatusOK {
+		t.Fatalf("expected status 200 on replay, got %d", code)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	slices.Sort(received)
+	if !slices.Equal(received, []string{"a", "b", "c"}) {
+		t.Fatalf("expected every item once and no replayed items, got: %v", received)
+	}
+}
+
+func TestWebhookHandler_LeavesPayloadUntouched(t *testing.T) {
+	var (
+		receivedPayload map[string]any
+		mu              sync.Mutex
+		wg              sync.WaitGroup
+	)
+
+	wg.Add(1)
+	mockDiscord := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		defer mu.Unlock()
+		_ = json.Unmarshal(body, &receivedPayload)
+		wg.Done()
+	}))
+	defer mockDiscord.Close()
+
+	testServer := &Server{
+		evaluator: condition.NewDefaultEvaluator(resolver.NewPathResolver()),
+		config: config.New([]types.Template{
+			{
+				Receiver:     "github",
+				Auth:         types.Auth{Flow: "github", HeaderSecretKey: "X-Hub-Signature-256", Secret: "s3cret"},
+				EventTypeKey: "X-GitHub-Event",
+				EventTypeIn:  "header",
+				Events: types.Events{
+					{
+						Event:      "push",
+						Conditions: []string{`{Body.head_commit.message} {startsWith} {Revert "}`},
+						Hooks: []types.Hook{
+							{
+								Name:        "MockDiscord",
+								EndpointKey: "Webhook-URL",
+								Body:        "discord.tmpl",
+							},
+						},
+					},
+				},
+			},
+		}, map[string]string{
+			"discord.tmpl": `{"content": "{{.head_commit.message}}"}`,
+		}, auth.NewDefault()),
+	}
+
+	message := "Revert \"Add C:\\temp\" support\"\n\nThis reverts commit abc."
+	payload, _ := json.Marshal(map[string]any{"head_commit": map[string]any{"message": message}})
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(payload)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/github", bytes.NewReader(payload))
+	req.Header.Set("X-GitHub-Event", "push")
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	req.Header.Set("Webhook-URL", mockDiscord.URL)
+
+	rr := httptest.NewRecorder()
+	testServer.RegisterRoutes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rr.Code)
+	}
+
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if receivedPayload["content"] != message {
+		t.Fatalf("expected message to round-trip, got: %q", receivedPayload["content"])
+	}
+}
+
 func getBodyTemplate(content string) string {
 	marshal, _ := json.Marshal(map[string]string{
 		"content": content,
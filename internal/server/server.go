@@ -4,8 +4,12 @@ import (
 	"fmt"
 	"github.com/AdamShannag/hookah/internal/condition"
 	"github.com/AdamShannag/hookah/internal/config"
+	"github.com/AdamShannag/hookah/internal/flow"
+	"github.com/AdamShannag/hookah/internal/handshake"
+	"github.com/AdamShannag/hookah/internal/replay"
 	"net/http"
 	"os"
+	"slices"
 	"strconv"
 	"time"
 )
@@ -14,14 +18,44 @@ type Server struct {
 	port      int
 	config    *config.Config
 	evaluator condition.Evaluator
+	replay    *replay.Guard
+	handshake handshake.Handshake
+
+	// authFailureStatus and unknownReceiverStatus are the responses sent when no template
+	// authenticated or the receiver is not configured; zero means 200.
+	authFailureStatus     int
+	unknownReceiverStatus int
 }
 
-func NewServer(config *config.Config, evaluator condition.Evaluator) *http.Server {
+func NewServer(config *config.Config, evaluator condition.Evaluator) (*http.Server, error) {
 	port, _ := strconv.Atoi(os.Getenv("PORT"))
+
+	authFailureStatus, err := statusFromEnv("AUTH_FAILURE_STATUS", http.StatusUnauthorized, http.StatusForbidden)
+	if err != nil {
+		return nil, err
+	}
+	unknownReceiverStatus, err := statusFromEnv("UNKNOWN_RECEIVER_STATUS", http.StatusNotFound)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = validateTemplates(config, evaluator); err != nil {
+		return nil, err
+	}
+
 	newServer := &Server{
-		port:      port,
-		config:    config,
-		evaluator: evaluator,
+		port:                  port,
+		config:                config,
+		evaluator:             evaluator,
+		replay:                replay.NewGuard(replay.NewMemoryStore()),
+		handshake:             handshake.NewDefault(),
+		authFailureStatus:     authFailureStatus,
+		unknownReceiverStatus: unknownReceiverStatus,
+	}
+
+	tlsConfig, err := newTLSConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure TLS: %w", err)
 	}
 
 	server := &http.Server{
@@ -30,7 +64,47 @@ func NewServer(config *config.Config, evaluator condition.Evaluator) *http.Serve
 		IdleTimeout:  time.Minute,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 30 * time.Second,
+		TLSConfig:    tlsConfig,
+	}
+
+	return server, nil
+}
+
+// validateTemplates checks conditions and replay policies up front, so a typo or a bad
+// pattern stops the server from starting instead of failing each matching request.
+func validateTemplates(config *config.Config, evaluator condition.Evaluator) error {
+	for _, template := range config.GetTemplateConfigs() {
+		if err := flow.ValidateIPAllowlist(template.Auth); err != nil {
+			return fmt.Errorf("receiver '%s' auth: %w", template.Receiver, err)
+		}
+		if template.Replay != nil {
+			if err := replay.Validate(*template.Replay); err != nil {
+				return fmt.Errorf("receiver '%s' replay: %w", template.Receiver, err)
+			}
+		}
+		for _, evt := range template.Events {
+			if _, err := condition.ParseMissingPolicy(evt.MissingPaths); err != nil {
+				return fmt.Errorf("receiver '%s' event '%s': %w", template.Receiver, evt.Event, err)
+			}
+			if err := evaluator.Validate(evt.Conditions); err != nil {
+				return fmt.Errorf("receiver '%s' event '%s': %w", template.Receiver, evt.Event, err)
+			}
+		}
+	}
+	return nil
+}
+
+// statusFromEnv reads an HTTP status from the environment, accepting 200 or one of allowed.
+func statusFromEnv(key string, allowed ...int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return 0, nil
+	}
+
+	status, err := strconv.Atoi(value)
+	if err == nil && (status == http.StatusOK || slices.Contains(allowed, status)) {
+		return status, nil
 	}
 
-	return server
+	return 0, fmt.Errorf("invalid %s '%s': expected 200 or one of %v", key, value, allowed)
 }
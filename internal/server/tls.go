@@ -0,0 +1,105 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// certReloader serves a certificate pair from disk, reloading it whenever either file's
+// modification time changes.
+type certReloader struct {
+	certPath string
+	keyPath  string
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	certTime time.Time
+	keyTime  time.Time
+}
+
+func newCertReloader(certPath, keyPath string) (*certReloader, error) {
+	reloader := &certReloader{certPath: certPath, keyPath: keyPath}
+	if _, err := reloader.GetCertificate(nil); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	certInfo, err := os.Stat(c.certPath)
+	if err != nil {
+		return nil, err
+	}
+	keyInfo, err := os.Stat(c.keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cert != nil && certInfo.ModTime().Equal(c.certTime) && keyInfo.ModTime().Equal(c.keyTime) {
+		return c.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certPath, c.keyPath)
+	if err != nil {
+		if c.cert != nil {
+			// Keep serving the last good pair while a rotation is half-written.
+			return c.cert, nil
+		}
+		return nil, fmt.Errorf("failed to load key pair: %w", err)
+	}
+
+	c.cert, c.certTime, c.keyTime = &cert, certInfo.ModTime(), keyInfo.ModTime()
+	return c.cert, nil
+}
+
+// newTLSConfig builds the listener TLS config from TLS_CERT_PATH and TLS_KEY_PATH. When
+// TLS_CLIENT_CA_PATH is set, client certificates are verified against that bundle; they
+// are required only if TLS_CLIENT_AUTH is "require". It returns nil when TLS is not configured.
+func newTLSConfig() (*tls.Config, error) {
+	certPath, keyPath := os.Getenv("TLS_CERT_PATH"), os.Getenv("TLS_KEY_PATH")
+	if certPath == "" && keyPath == "" {
+		return nil, nil
+	}
+	if certPath == "" || keyPath == "" {
+		return nil, errors.New("both TLS_CERT_PATH and TLS_KEY_PATH must be set")
+	}
+
+	reloader, err := newCertReloader(certPath, keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	caPath := os.Getenv("TLS_CLIENT_CA_PATH")
+	if caPath == "" {
+		return config, nil
+	}
+
+	bundle, err := os.ReadFile(caPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA bundle: %w", err)
+	}
+	config.ClientCAs = x509.NewCertPool()
+	if !config.ClientCAs.AppendCertsFromPEM(bundle) {
+		return nil, errors.New("no certificates found in client CA bundle")
+	}
+
+	config.ClientAuth = tls.VerifyClientCertIfGiven
+	if os.Getenv("TLS_CLIENT_AUTH") == "require" {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCertReloader_ReloadsOnChange(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+
+	writeKeyPair(t, certPath, keyPath, "first")
+	reloader, err := newCertReloader(certPath, keyPath)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if name := leafName(t, reloader); name != "first" {
+		t.Fatalf("expected first certificate, got %s", name)
+	}
+
+	writeKeyPair(t, certPath, keyPath, "second")
+	later := time.Now().Add(time.Minute)
+	_ = os.Chtimes(certPath, later, later)
+	_ = os.Chtimes(keyPath, later, later)
+
+	if name := leafName(t, reloader); name != "second" {
+		t.Fatalf("expected reloaded certificate, got %s", name)
+	}
+
+	_ = os.WriteFile(keyPath, []byte("half-written"), 0o600)
+	_ = os.Chtimes(keyPath, later.Add(time.Minute), later.Add(time.Minute))
+
+	if name := leafName(t, reloader); name != "second" {
+		t.Fatalf("expected last good certificate on broken rotation, got %s", name)
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeKeyPair(t, certPath, keyPath, "server")
+
+	t.Run("disabled", func(t *testing.T) {
+		config, err := newTLSConfig()
+		if err != nil || config != nil {
+			t.Fatalf("expected no TLS config, got %v, %v", config, err)
+		}
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		t.Setenv("TLS_CERT_PATH", certPath)
+		if _, err := newTLSConfig(); err == nil {
+			t.Fatal("expected error when only the certificate is set")
+		}
+	})
+
+	t.Run("client CA", func(t *testing.T) {
+		t.Setenv("TLS_CERT_PATH", certPath)
+		t.Setenv("TLS_KEY_PATH", keyPath)
+		t.Setenv("TLS_CLIENT_CA_PATH", certPath)
+		t.Setenv("TLS_CLIENT_AUTH", "require")
+
+		config, err := newTLSConfig()
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if config.ClientCAs == nil || config.ClientAuth.String() != "RequireAndVerifyClientCert" {
+			t.Errorf("expected required client certificates, got %v", config.ClientAuth)
+		}
+	})
+}
+
+func writeKeyPair(t *testing.T, certPath, keyPath, commonName string) {
+	t.Helper()
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	_ = os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	_ = os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+}
+
+func leafName(t *testing.T, reloader *certReloader) string {
+	t.Helper()
+
+	cert, err := reloader.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+	return leaf.Subject.CommonName
+}
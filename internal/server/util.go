@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"github.com/AdamShannag/hookah/internal/condition"
+	"github.com/AdamShannag/hookah/internal/decode"
 	"github.com/AdamShannag/hookah/internal/render"
 	"github.com/AdamShannag/hookah/internal/types"
 	"log"
@@ -30,7 +32,7 @@ func (s *Server) handleTemplate(tmpl types.Template, headers http.Header, body m
 }
 
 func (s *Server) processEvent(evt types.Event, headers http.Header, body map[string]any) {
-	ok, err := s.evaluator.EvaluateAll(evt.Conditions, headers, body)
+	ok, err := s.evaluator.EvaluateAllWithPolicy(condition.MissingPolicy(evt.MissingPaths), evt.Conditions, headers, body)
 	if err != nil {
 		log.Printf("[Condition] Evaluation error: %v", err)
 		return
@@ -66,6 +68,108 @@ func (s *Server) triggerHook(hook types.Hook, body map[string]any, headers http.
 	}
 }
 
+func (s *Server) answerHandshake(w http.ResponseWriter, r *http.Request, templates []types.Template, payload []byte) bool {
+	if s.handshake == nil {
+		return false
+	}
+
+	for _, tmpl := range templates {
+		if tmpl.Handshake == nil {
+			continue
+		}
+
+		resp, ok := s.handshake.Answer(*tmpl.Handshake, r, payload)
+		if !ok {
+			continue
+		}
+
+		log.Printf("[Handshake] Answered %s verification for receiver: %s", tmpl.Handshake.Type, tmpl.Receiver)
+		w.Header().Set("Content-Type", resp.ContentType)
+		w.WriteHeader(resp.Status)
+		_, _ = w.Write(resp.Body)
+		return true
+	}
+
+	return false
+}
+
+// selectResponse picks the reply for the source: the response of the first matching event
+// that declares one, otherwise the first template-level response.
+func (s *Server) selectResponse(templates []types.Template, headers http.Header, body map[string]any) *types.Response {
+	var fallback *types.Response
+	for _, tmpl := range templates {
+		if fallback == nil {
+			fallback = tmpl.Response
+		}
+
+		eventType, err := extractEventType(tmpl, headers, body)
+		if err != nil {
+			continue
+		}
+
+		for _, evt := range tmpl.Events.GetEvents(eventType) {
+			if evt.Response == nil {
+				continue
+			}
+			if ok, evalErr := s.evaluator.EvaluateAllWithPolicy(condition.MissingPolicy(evt.MissingPaths), evt.Conditions, headers, body); evalErr == nil && ok {
+				return evt.Response
+			}
+		}
+	}
+	return fallback
+}
+
+func (s *Server) writeResponse(w http.ResponseWriter, response *types.Response, body map[string]any) {
+	contentType := response.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	renderFunc := render.ToBytes
+	if strings.Contains(contentType, "json") {
+		renderFunc = render.ToJSON
+	}
+
+	rendered, err := renderFunc(s.config.GetTemplate(response.Body), body)
+	if err != nil {
+		log.Printf("[Response] Failed to render template (%s): %v", response.Body, err)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	status := response.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_, _ = w.Write(rendered)
+}
+
+// decodePayload decodes the request body into one map per event. Payload options are
+// merged across the authenticated templates: their JSON fields are combined, and batch
+// mode applies to the whole receiver if any template enables it.
+func decodePayload(contentType string, payload []byte, templates []types.Template) ([]map[string]any, error) {
+	var jsonFields []string
+	batch := false
+	for _, tmpl := range templates {
+		if tmpl.Payload != nil {
+			jsonFields = append(jsonFields, tmpl.Payload.JSONFields...)
+			batch = batch || tmpl.Payload.Batch
+		}
+	}
+
+	if batch {
+		return decode.Batch(contentType, payload, jsonFields)
+	}
+
+	body, err := decode.Body(contentType, payload, jsonFields)
+	if err != nil {
+		return nil, err
+	}
+	return []map[string]any{body}, nil
+}
+
 func extractEventType(tmpl types.Template, headers http.Header, body map[string]any) (string, error) {
 	switch tmpl.EventTypeIn {
 	case "header":
@@ -97,7 +201,3 @@ func postJSON(url string, data map[string]any) error {
 	_, err = http.Post(url, "application/json", bytes.NewBuffer(jsonData))
 	return err
 }
-
-func escapeEscapedQuotes(payload []byte) []byte {
-	return []byte(strings.ReplaceAll(string(payload), `\"`, `\\\"`))
-}
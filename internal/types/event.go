@@ -5,7 +5,11 @@ type Events []Event
 type Event struct {
 	Event      string   `json:"event,omitempty"`
 	Conditions []string `json:"conditions,omitempty"`
-	Hooks      []Hook   `json:"hooks,omitempty"`
+	// MissingPaths is what a condition does when a Body path is absent: "error" (the
+	// default) aborts the event, "false" fails that comparison and "null" compares it as null.
+	MissingPaths string    `json:"missing_paths,omitempty"`
+	Hooks        []Hook    `json:"hooks,omitempty"`
+	Response     *Response `json:"response,omitempty"`
 }
 
 func (e Events) GetEvents(event string) (events []Event) {
@@ -1,11 +1,15 @@
 package types
 
 type Template struct {
-	Receiver     string `json:"receiver"`
-	Auth         Auth   `json:"auth"`
-	EventTypeIn  string `json:"event_type_in"`
-	EventTypeKey string `json:"event_type_key"`
-	Events       Events `json:"events,omitempty"`
+	Receiver     string     `json:"receiver"`
+	Auth         Auth       `json:"auth"`
+	EventTypeIn  string     `json:"event_type_in"`
+	EventTypeKey string     `json:"event_type_key"`
+	Events       Events     `json:"events,omitempty"`
+	Replay       *Replay    `json:"replay,omitempty"`
+	Handshake    *Handshake `json:"handshake,omitempty"`
+	Response     *Response  `json:"response,omitempty"`
+	Payload      *Payload   `json:"payload,omitempty"`
 }
 
 type Hook struct {
@@ -15,7 +19,61 @@ type Hook struct {
 }
 
 type Auth struct {
-	Flow            string `json:"flow"`
-	HeaderSecretKey string `json:"header_secret_key,omitempty"`
-	Secret          string `json:"secret"`
+	Flow               string   `json:"flow"`
+	All                []Auth   `json:"all,omitempty"`
+	Any                []Auth   `json:"any,omitempty"`
+	HeaderSecretKey    string   `json:"header_secret_key,omitempty"`
+	Secret             string   `json:"secret"`
+	Tolerance          string   `json:"tolerance,omitempty"`
+	JWKSPath           string   `json:"jwks_path,omitempty"`
+	Issuer             string   `json:"issuer,omitempty"`
+	Audience           string   `json:"audience,omitempty"`
+	AllowedCIDRs       []string `json:"allowed_cidrs,omitempty"`
+	TrustedProxies     []string `json:"trusted_proxies,omitempty"`
+	ForwardedHeader    string   `json:"forwarded_header,omitempty"`
+	ClientSubjects     []string `json:"client_subjects,omitempty"`
+	ClientSANs         []string `json:"client_sans,omitempty"`
+	ClientFingerprints []string `json:"client_fingerprints,omitempty"`
+}
+
+// Name describes the policy for logs: the flow name, or "all"/"any" for composites.
+func (a Auth) Name() string {
+	switch {
+	case len(a.All) > 0:
+		return "all"
+	case len(a.Any) > 0:
+		return "any"
+	default:
+		return a.Flow
+	}
+}
+
+// Payload controls how request bodies are decoded. With Batch set, a top-level JSON
+// array or an NDJSON stream is split into one event per item.
+type Payload struct {
+	JSONFields []string `json:"json_fields,omitempty"`
+	Batch      bool     `json:"batch,omitempty"`
+}
+
+// Response is a template rendered synchronously as the reply to the webhook source.
+type Response struct {
+	Body        string `json:"body"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+}
+
+type Handshake struct {
+	Type        string `json:"type"`
+	VerifyToken string `json:"verify_token,omitempty"`
+}
+
+// Replay rejects repeated nonces. With a timestamp, requests older than MaxAge are
+// rejected and nonces are remembered while their timestamp is accepted; without one,
+// nonces are only remembered for MaxAge, which must then be set explicitly.
+type Replay struct {
+	NonceIn      string `json:"nonce_in"`
+	NonceKey     string `json:"nonce_key"`
+	TimestampIn  string `json:"timestamp_in,omitempty"`
+	TimestampKey string `json:"timestamp_key,omitempty"`
+	MaxAge       string `json:"max_age,omitempty"`
 }